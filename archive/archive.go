@@ -0,0 +1,224 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	cm "github.com/mainak55512/qwe/commit"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	res "github.com/mainak55512/qwe/reconstruct"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// Archive formats, selected from the extension of the output file
+const (
+	formatTar = iota
+	formatTarGz
+	formatZip
+)
+
+// A file of the archived group version
+type entry struct {
+	name    string
+	content []byte
+}
+
+// Writes archive entries in one of the supported formats
+type writer interface {
+	add(e entry, modTime time.Time) error
+	Close() error
+}
+
+type tarWriter struct {
+	tw *tar.Writer
+	gw *gzip.Writer
+}
+
+func (w *tarWriter) add(e entry, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    e.name,
+		Mode:    0644,
+		Size:    int64(len(e.content)),
+		ModTime: modTime,
+		Format:  tar.FormatPAX,
+	}
+	if err := w.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(e.content)
+	return err
+}
+
+func (w *tarWriter) Close() error {
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	if w.gw != nil {
+		return w.gw.Close()
+	}
+	return nil
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func (w *zipWriter) add(e entry, modTime time.Time) error {
+	header := &zip.FileHeader{
+		Name:     e.name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	}
+	header.SetMode(0644)
+	f, err := w.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(e.content)
+	return err
+}
+
+func (w *zipWriter) Close() error {
+	return w.zw.Close()
+}
+
+// Returns the archive format for the output file name
+func formatOf(output string) (int, error) {
+	name := strings.ToLower(output)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return formatTarGz, nil
+	case strings.HasSuffix(name, ".tar"):
+		return formatTar, nil
+	case strings.HasSuffix(name, ".zip"):
+		return formatZip, nil
+	}
+	return 0, er.ArchiveFormatErr
+}
+
+// Converts a tracked file path to a relative path inside the archive
+func entryName(filePath string) string {
+	name := filepath.ToSlash(filepath.Clean(filePath))
+	for {
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(name, "/"), "../")
+		if trimmed == name {
+			return name
+		}
+		name = trimmed
+	}
+}
+
+// Reconstructs every file of a group version, sorted by name
+func groupEntries(groupName string, commitID int) ([]entry, string, error) {
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return nil, "", err
+	}
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	gr, ok := groupTracker[utl.Hasher(groupName)]
+	if !ok {
+		return nil, "", er.InvalidGroup
+	}
+	if commitID < 0 || commitID > len(gr.VersionOrder)-1 {
+		return nil, "", er.InvalidCommitNo
+	}
+	version := gr.Versions[gr.VersionOrder[commitID]]
+
+	var entries []entry
+	var unresolved []string
+	for fileID, f := range version.Files {
+		val, ok := tracker[fileID]
+		if !ok {
+			continue
+		}
+		commitNumber, ok := val.CommitID(f.UID)
+		if !ok {
+			unresolved = append(unresolved, f.FileName)
+			continue
+		}
+		content, err := res.Content(val, commitNumber)
+		if err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, entry{name: entryName(f.FileName), content: content})
+	}
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		fmt.Printf("Warning: group commit %d has no commit of %s, left out of the archive\n", commitID, strings.Join(unresolved, ", "))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+	return entries, version.TimeStamp, nil
+}
+
+// Writes the files of a group version to a tar, tar.gz or zip archive without touching the working files
+func Create(groupName string, commitID int, output string) (err error) {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+
+	format, err := formatOf(output)
+	if err != nil {
+		return err
+	}
+
+	entries, timeStamp, err := groupEntries(groupName, commitID)
+	if err != nil {
+		return err
+	}
+	modTime, parseErr := time.ParseInLocation(cm.TimeStampLayout, timeStamp, time.Local)
+	if parseErr != nil {
+		modTime = time.Now()
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return er.OutputWriteErr
+	}
+	// Never leave a partial archive behind
+	defer func() {
+		if closeErr := file.Close(); err == nil && closeErr != nil {
+			err = er.OutputWriteErr
+		}
+		if err != nil {
+			os.Remove(output)
+		}
+	}()
+
+	var w writer
+	switch format {
+	case formatZip:
+		w = &zipWriter{zw: zip.NewWriter(file)}
+	case formatTarGz:
+		gw := gzip.NewWriter(file)
+		w = &tarWriter{tw: tar.NewWriter(gw), gw: gw}
+	default:
+		w = &tarWriter{tw: tar.NewWriter(file)}
+	}
+
+	for _, e := range entries {
+		if err = w.add(e, modTime); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Archived %d files of %s version %d to %s\n", len(entries), groupName, commitID, output)
+	return nil
+}
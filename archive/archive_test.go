@@ -0,0 +1,171 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	cm "github.com/mainak55512/qwe/commit"
+	in "github.com/mainak55512/qwe/initializer"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+func TestCreate(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.Mkdir("conf", 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	files := map[string]string{"conf/app.ini": "port=80\n", "logo.bin": "\x00\x01logo"}
+	for filePath, content := range files {
+		if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+	if err := in.GroupInit("release"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	if err := tr.StartGroupTracking("release", []string{"conf/app.ini", "logo.bin"}); err != nil {
+		t.Fatalf("failed to track group: %v", err)
+	}
+	if err := os.WriteFile("conf/app.ini", []byte("port=8080\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	if err := cm.CommitGroup("release", "Change port", false, false); err != nil {
+		t.Fatalf("failed to commit group: %v", err)
+	}
+	if err := os.WriteFile("conf/app.ini", []byte("uncommitted\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+
+	if err := Create("release", 0, "release.tar.gz"); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	f, err := os.Open("release.tar.gz")
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	got := make(map[string]string)
+	r := tar.NewReader(gr)
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read archive: %v", err)
+		}
+		content, _ := io.ReadAll(r)
+		got[header.Name] = string(content)
+	}
+	for filePath, content := range files {
+		if got[filePath] != content {
+			t.Errorf("%s: expected %q, got %q", filePath, content, got[filePath])
+		}
+	}
+
+	if err := Create("release", 1, "release.zip"); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	zr, err := zip.OpenReader("release.zip")
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer zr.Close()
+	for _, zf := range zr.File {
+		if zf.Name != "conf/app.ini" {
+			continue
+		}
+		rc, _ := zf.Open()
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(content) != "port=8080\n" {
+			t.Errorf("expected committed content, got %q", content)
+		}
+	}
+
+	content, _ := os.ReadFile("conf/app.ini")
+	if string(content) != "uncommitted\n" {
+		t.Errorf("working file was modified: %q", content)
+	}
+	if err := Create("release", 0, "release.rar"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+	if err := Create("release", 5, "release.tar"); err == nil {
+		t.Error("expected error for invalid commit number")
+	}
+	if _, err := os.Stat("release.tar"); err == nil {
+		t.Error("expected no archive for invalid commit number")
+	}
+}
+
+func TestCreateWarnsAboutUnresolvedFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	for _, filePath := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filePath, []byte("base\n"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := tr.StartGroupTracking("docs", []string{"a.txt", "b.txt"}); err != nil {
+		t.Fatalf("failed to track files in group: %v", err)
+	}
+
+	// The group version refers to a commit of b.txt that is not part of its history
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read group tracker: %v", err)
+	}
+	gr := groupTracker[utl.Hasher("docs")]
+	gr.Versions[gr.VersionOrder[0]].Files[utl.Hasher("b.txt")] = tr.FileDetails{FileName: "b.txt", UID: "missing"}
+	content, err := json.Marshal(groupTracker)
+	if err != nil {
+		t.Fatalf("failed to marshal group tracker: %v", err)
+	}
+	if err := tr.SaveTracker(tr.GroupTrackerType, content); err != nil {
+		t.Fatalf("failed to save group tracker: %v", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	createErr := Create("docs", 0, "docs.zip")
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+	if createErr != nil {
+		t.Fatalf("Create() failed: %v", createErr)
+	}
+	if !strings.Contains(string(out), "Warning: group commit 0 has no commit of b.txt") {
+		t.Errorf("expected a warning about b.txt, got %q", out)
+	}
+	zr, err := zip.OpenReader("docs.zip")
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 1 || zr.File[0].Name != "a.txt" {
+		t.Errorf("expected only a.txt in the archive, got %d files", len(zr.File))
+	}
+}
@@ -27,15 +27,21 @@ func CheckBinFile(filePath string) (bool, error) {
 	if err != nil && !(errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
 		return false, err
 	}
-	for i := 0; i < size; i++ {
-		runeValue := rune(buffer[i])
-		if buffer[i] == 0 && !unicode.IsSpace(runeValue) && !unicode.IsPrint(runeValue) {
-			return true, nil
+	return IsBinary(buffer[:size]), nil
+}
+
+// Checks if content is binary, only the first 1024 bytes are inspected as CheckBinFile does
+func IsBinary(content []byte) bool {
+	for i := 0; i < len(content) && i < 1024; i++ {
+		runeValue := rune(content[i])
+		if content[i] == 0 && !unicode.IsSpace(runeValue) && !unicode.IsPrint(runeValue) {
+			return true
 		}
 	}
-	return false, nil
+	return false
 }
 
+// Compares two files byte by byte, returns true if they are equal
 func CheckBinDiff(file_one, file_two string) (bool, error) {
 	file_1, err := os.Open(file_one)
 	if err != nil {
@@ -49,94 +55,120 @@ func CheckBinDiff(file_one, file_two string) (bool, error) {
 	}
 	defer file_2.Close()
 
-	for {
-		buff1 := make([]byte, 8192)
-		buff2 := make([]byte, 8192)
-
-		_, err1 := file_1.Read(buff1)
-		_, err2 := file_2.Read(buff2)
-
-		if err1 != nil || err2 != nil {
-			if err1 == io.EOF && err2 == io.EOF {
-				return true, nil
-			} else if err1 == io.EOF || err2 == io.EOF {
-				return false, nil
-			} else {
-				return false, fmt.Errorf("Error while comparing!")
-			}
-		}
+	return SameContent(file_1, file_2)
+}
 
-		if !bytes.Equal(buff1, buff2) {
+// Compares two streams byte by byte, returns true if they are equal
+func SameContent(r1, r2 io.Reader) (bool, error) {
+	buff1 := make([]byte, 8192)
+	buff2 := make([]byte, 8192)
+	for {
+		n1, err1 := io.ReadFull(r1, buff1)
+		n2, err2 := io.ReadFull(r2, buff2)
+		if !bytes.Equal(buff1[:n1], buff2[:n2]) {
 			return false, nil
 		}
+		end1 := errors.Is(err1, io.EOF) || errors.Is(err1, io.ErrUnexpectedEOF)
+		end2 := errors.Is(err2, io.EOF) || errors.Is(err2, io.ErrUnexpectedEOF)
+		if (err1 != nil && !end1) || (err2 != nil && !end2) {
+			return false, fmt.Errorf("Error while comparing!")
+		}
+		if end1 || end2 {
+			return end1 && end2, nil
+		}
 	}
 }
 
+// Compares a binary object with a file without writing a plain copy of the object
+func MatchesFile(objID, filePath string) (bool, error) {
+	object, err := Open(objID)
+	if err != nil {
+		return false, err
+	}
+	defer object.Close()
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	return SameContent(object, file)
+}
+
+// Restores the content of a binary object to filePath, deltas are applied to their base versions
 func RevertBinFile(filePath, fileObjID string) error {
-	commitFile := ".qwe/_object/" + fileObjID
-	src, err := os.Open(commitFile)
+	src, err := Open(fileObjID)
 	if err != nil {
 		return err
 	}
+	defer src.Close()
+
 	dest, err := os.Create(filePath)
 	if err != nil {
 		return err
 	}
 	if _, err = io.Copy(dest, src); err != nil {
+		dest.Close()
 		return err
 	}
-	if err = cp.DecompressFile(filePath); err != nil {
-		return err
-	}
-	return nil
+	return dest.Close()
 }
 
+// Stores the binary file as a new object if it differs from the last commit, returns er.NoFileOrDiff otherwise.
+// The object is a delta against the last commit unless either version is too large to compute one.
 func CommitBinFile(filePath, lastCommit string) (string, error) {
-	src, err := os.Open(filePath)
+	info, err := os.Stat(filePath)
 	if err != nil {
 		return "", err
 	}
-	fileObjID := "_bin_" + utl.Hasher(fmt.Sprintf("%s%d", filePath, time.Now().UnixNano()))
-	target := ".qwe/_object/" + fileObjID
-	dest, err := os.Create(target)
+	if info.Size() > MaxDeltaSize {
+		return commitFullCopy(filePath, lastCommit)
+	}
+
+	prev, fits, err := readSmallObject(lastCommit)
 	if err != nil {
 		return "", err
 	}
-
-	lastCommittedFile, err := os.Open(".qwe/_object/" + lastCommit)
+	if !fits {
+		return commitFullCopy(filePath, lastCommit)
+	}
+	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return "", err
 	}
-	defer lastCommittedFile.Close()
+	if bytes.Equal(prev, content) {
+		return "", er.NoFileOrDiff
+	}
+	return WriteObject(filePath, lastCommit, prev, content)
+}
 
-	if _, err := io.Copy(dest, lastCommittedFile); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+// Stores the binary file as a full copy, the file is compared and compressed while it is read
+func commitFullCopy(filePath, lastCommit string) (string, error) {
+	src, err := os.Open(filePath)
+	if err != nil {
 		return "", err
 	}
+	defer src.Close()
 
-	if err = cp.DecompressFile(target); err != nil {
+	lastCommittedFile, err := Open(lastCommit)
+	if err != nil {
 		return "", err
 	}
+	defer lastCommittedFile.Close()
 
-	isEq, err := CheckBinDiff(target, filePath)
+	isEq, err := SameContent(lastCommittedFile, src)
 	if err != nil {
 		return "", err
 	}
-
-	if !isEq {
-		dest, err = os.Create(target)
-		if err != nil {
-			return "", err
-		}
-		if _, err := io.Copy(dest, src); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
-			return "", err
-		}
-		if err = cp.CompressFile(target); err != nil {
-			return "", err
-		}
-	} else {
-		os.Remove(target)
+	if isEq {
 		return "", er.NoFileOrDiff
 	}
 
+	if _, err = src.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	fileObjID := fullPrefix + utl.Hasher(fmt.Sprintf("%s%d", filePath, time.Now().UnixNano()))
+	if err = cp.Copy(".qwe/_object/"+fileObjID, src); err != nil {
+		return "", err
+	}
 	return fileObjID, nil
 }
@@ -0,0 +1,398 @@
+package binaryhandler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	cp "github.com/mainak55512/qwe/compressor"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+)
+
+// Binary commits are stored as deltas against the previous version of the file. A delta object starts with the line
+// '<base object id> <depth> <size>' followed by copy and insert operations, depth counts the deltas down to a full copy.
+const (
+	objectDir    = ".qwe/_object"
+	deltaPrefix  = "_bin_delta_"
+	fullPrefix   = "_bin_"
+	opCopy       = 'c' // copy <offset> <length> from the base version
+	opInsert     = 'i' // insert <length> literal bytes
+	minBlockSize = 64
+	maxBlocks    = 1 << 20
+)
+
+var (
+	// A full copy is stored after this many deltas, so a version never needs more deltas to be rebuilt
+	SnapshotInterval = 16
+
+	// Files larger than this are always stored as full copies. Both versions are held in memory to compute a delta,
+	// group commits compute one per worker at a time.
+	MaxDeltaSize int64 = 16 << 20
+)
+
+// Reports whether an object is a delta against another binary object
+func IsDelta(objID string) bool {
+	return strings.HasPrefix(objID, deltaPrefix)
+}
+
+type deltaHeader struct {
+	base  string
+	depth int
+	size  int64
+}
+
+// Reads the header line of a delta, the reader is left at the first operation
+func readHeader(r *bufio.Reader) (deltaHeader, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return deltaHeader{}, er.InvalidDelta
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return deltaHeader{}, er.InvalidDelta
+	}
+	var h deltaHeader
+	h.base = fields[0]
+	if h.depth, err = strconv.Atoi(fields[1]); err != nil {
+		return h, er.InvalidDelta
+	}
+	if h.size, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+		return h, er.InvalidDelta
+	}
+	return h, nil
+}
+
+// Reads the header of a delta object stored in dir
+func objectHeader(dir, objID string) (deltaHeader, error) {
+	object, err := cp.Open(filepath.Join(dir, objID))
+	if err != nil {
+		return deltaHeader{}, fmt.Errorf("%w: %s", er.MissingObject, objID)
+	}
+	defer object.Close()
+	return readHeader(bufio.NewReader(object))
+}
+
+// Returns the number of deltas that are applied to rebuild an object, 0 for full copies
+func depth(objID string) (int, error) {
+	if !IsDelta(objID) {
+		return 0, nil
+	}
+	h, err := objectHeader(objectDir, objID)
+	if err != nil {
+		return 0, err
+	}
+	return h.depth, nil
+}
+
+// Returns the objects stored in dir together with the objects their deltas are based on.
+// Bases are listed before the objects that depend on them, every object is listed once.
+func WithDependencies(dir string, objects []string) ([]string, error) {
+	var result []string
+	seen := make(map[string]struct{}, len(objects))
+	for _, objID := range objects {
+		var chain []string
+		for id := objID; ; {
+			if _, ok := seen[id]; ok {
+				break
+			}
+			seen[id] = struct{}{}
+			chain = append(chain, id)
+			if !IsDelta(id) {
+				break
+			}
+			h, err := objectHeader(dir, id)
+			if err != nil {
+				return nil, err
+			}
+			id = h.base
+		}
+		for i := len(chain) - 1; i >= 0; i-- {
+			result = append(result, chain[i])
+		}
+	}
+	return result, nil
+}
+
+// Opens a binary object of the repository in the working directory, deltas are applied to their base versions.
+// Full copies are decompressed while they are read, deltas are rebuilt in a temporary file first.
+func Open(objID string) (io.ReadCloser, error) {
+	if !IsDelta(objID) {
+		return cp.Open(filepath.Join(objectDir, objID))
+	}
+
+	// Collect the deltas down to the full copy
+	chain := []string{objID}
+	for IsDelta(chain[len(chain)-1]) {
+		if len(chain) > SnapshotInterval*4 {
+			return nil, er.InvalidDelta
+		}
+		h, err := objectHeader(objectDir, chain[len(chain)-1])
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, h.base)
+	}
+
+	current, err := materialize(func(w io.Writer) error {
+		src, err := cp.Open(filepath.Join(objectDir, chain[len(chain)-1]))
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(w, src)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := len(chain) - 2; i >= 0; i-- {
+		base := current
+		current, err = materialize(func(w io.Writer) error {
+			return applyObject(chain[i], base, w)
+		})
+		base.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err = current.Seek(0, io.SeekStart); err != nil {
+		current.Close()
+		return nil, err
+	}
+	return current, nil
+}
+
+// Reads the content of a binary object of the repository in the working directory
+func ReadObject(objID string) ([]byte, error) {
+	object, err := Open(objID)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+	content, err := io.ReadAll(object)
+	if err != nil {
+		return nil, er.BufCopyErr
+	}
+	return content, nil
+}
+
+// Reads the content of a binary object unless it is larger than MaxDeltaSize, fits reports whether it was read
+func readSmallObject(objID string) (content []byte, fits bool, err error) {
+	object, err := Open(objID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer object.Close()
+	content, err = io.ReadAll(io.LimitReader(object, MaxDeltaSize+1))
+	if err != nil {
+		return nil, false, er.BufCopyErr
+	}
+	if int64(len(content)) > MaxDeltaSize {
+		return nil, false, nil
+	}
+	return content, true, nil
+}
+
+// Temporary file that is removed when it is closed
+type tempFile struct {
+	*os.File
+}
+
+func (f tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
+
+// Writes content to a new temporary file inside .qwe, rebuilt versions stay on the file system of the repository
+func materialize(write func(w io.Writer) error) (tempFile, error) {
+	file, err := os.CreateTemp(filepath.Dir(objectDir), "_tmp_bin_*")
+	if err != nil {
+		return tempFile{}, err
+	}
+	tmp := tempFile{file}
+	bw := bufio.NewWriter(tmp)
+	if err = write(bw); err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		tmp.Close()
+		return tempFile{}, err
+	}
+	return tmp, nil
+}
+
+// Applies a delta object to the content of its base version
+func applyObject(objID string, base io.ReaderAt, w io.Writer) error {
+	object, err := cp.Open(filepath.Join(objectDir, objID))
+	if err != nil {
+		return fmt.Errorf("%w: %s", er.MissingObject, objID)
+	}
+	defer object.Close()
+	r := bufio.NewReader(object)
+	h, err := readHeader(r)
+	if err != nil {
+		return err
+	}
+	return applyDelta(base, r, w, h.size)
+}
+
+// Writes the content described by the operations of a delta, size is the expected length of the result
+func applyDelta(base io.ReaderAt, r *bufio.Reader, w io.Writer, size int64) error {
+	var written int64
+	for {
+		op, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return er.InvalidDelta
+		}
+		switch op {
+		case opCopy:
+			offset, err1 := binary.ReadUvarint(r)
+			length, err2 := binary.ReadUvarint(r)
+			if err1 != nil || err2 != nil {
+				return er.InvalidDelta
+			}
+			n, err := io.Copy(w, io.NewSectionReader(base, int64(offset), int64(length)))
+			if err != nil {
+				return err
+			}
+			if n != int64(length) {
+				return er.InvalidDelta
+			}
+			written += n
+		case opInsert:
+			length, err := binary.ReadUvarint(r)
+			if err != nil {
+				return er.InvalidDelta
+			}
+			n, err := io.CopyN(w, r, int64(length))
+			if err != nil {
+				return er.InvalidDelta
+			}
+			written += n
+		default:
+			return er.InvalidDelta
+		}
+	}
+	if written != size {
+		return er.InvalidDelta
+	}
+	return nil
+}
+
+// Computes the operations that turn prev into next. Blocks of prev are indexed by a rolling hash,
+// matching blocks of next are extended in both directions and copied, everything else is inserted.
+func computeDelta(prev, next []byte) []byte {
+	var ops bytes.Buffer
+	var varint [binary.MaxVarintLen64]byte
+	insert := func(literal []byte) {
+		if len(literal) == 0 {
+			return
+		}
+		ops.WriteByte(opInsert)
+		ops.Write(varint[:binary.PutUvarint(varint[:], uint64(len(literal)))])
+		ops.Write(literal)
+	}
+	copyOp := func(offset, length int) {
+		ops.WriteByte(opCopy)
+		ops.Write(varint[:binary.PutUvarint(varint[:], uint64(offset))])
+		ops.Write(varint[:binary.PutUvarint(varint[:], uint64(length))])
+	}
+
+	blockSize := max(minBlockSize, (len(prev)+maxBlocks-1)/maxBlocks)
+	if len(prev) < blockSize || len(next) < blockSize {
+		insert(next)
+		return ops.Bytes()
+	}
+
+	index := make(map[uint32]int, len(prev)/blockSize)
+	for offset := 0; offset+blockSize <= len(prev); offset += blockSize {
+		h := rollingHash(prev[offset : offset+blockSize])
+		if _, ok := index[h]; !ok {
+			index[h] = offset
+		}
+	}
+	pow := uint32(1)
+	for range blockSize - 1 {
+		pow *= hashBase
+	}
+
+	literal := 0 // start of the bytes of next that are not covered yet
+	pos := 0
+	h := rollingHash(next[:blockSize])
+	for pos+blockSize <= len(next) {
+		offset, ok := index[h]
+		if ok && bytes.Equal(prev[offset:offset+blockSize], next[pos:pos+blockSize]) {
+			start, end := pos, pos+blockSize
+			for start > literal && offset > 0 && prev[offset-1] == next[start-1] {
+				start--
+				offset--
+			}
+			length := end - start
+			for end < len(next) && offset+length < len(prev) && prev[offset+length] == next[end] {
+				end++
+				length++
+			}
+			insert(next[literal:start])
+			copyOp(offset, length)
+			literal, pos = end, end
+			if pos+blockSize <= len(next) {
+				h = rollingHash(next[pos : pos+blockSize])
+			}
+			continue
+		}
+		if pos+blockSize < len(next) {
+			h = (h-uint32(next[pos])*pow)*hashBase + uint32(next[pos+blockSize])
+		}
+		pos++
+	}
+	insert(next[literal:])
+	return ops.Bytes()
+}
+
+const hashBase = 16777619
+
+func rollingHash(block []byte) uint32 {
+	var h uint32
+	for _, b := range block {
+		h = h*hashBase + uint32(b)
+	}
+	return h
+}
+
+// Stores content as a new binary object of filePath and returns its id. If prevID is the object of the previous
+// version and prev its content, the object is a delta against it unless a full copy is due or the delta does not pay off.
+func WriteObject(filePath, prevID string, prev, content []byte) (string, error) {
+	hash := utl.Hasher(fmt.Sprintf("%s%d", filePath, time.Now().UnixNano()))
+	object := content
+	objID := fullPrefix + hash
+	if prevID != "" && int64(len(content)) <= MaxDeltaSize {
+		prevDepth, err := depth(prevID)
+		if err != nil {
+			return "", err
+		}
+		if prevDepth+1 < SnapshotInterval {
+			ops := computeDelta(prev, content)
+			if len(ops) < len(content)-len(content)/8 {
+				header := fmt.Sprintf("%s %d %d\n", prevID, prevDepth+1, len(content))
+				object = append([]byte(header), ops...)
+				objID = deltaPrefix + hash
+			}
+		}
+	}
+	if err := cp.WriteFile(filepath.Join(objectDir, objID), object); err != nil {
+		return "", er.OutputWriteErr
+	}
+	return objID, nil
+}
@@ -0,0 +1,171 @@
+package binaryhandler
+
+import (
+	"bufio"
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func randomBytes(r *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	r.Read(b)
+	return b
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	prev := randomBytes(r, 256<<10)
+
+	edited := bytes.Clone(prev)
+	copy(edited[1000:], randomBytes(r, 300))                                           // overwritten range
+	edited = append(edited[:50000], append(randomBytes(r, 777), edited[50000:]...)...) // inserted range
+	edited = append(edited[:120000], edited[130000:]...)                               // removed range
+
+	cases := map[string][2][]byte{
+		"edited":    {prev, edited},
+		"unchanged": {prev, prev},
+		"appended":  {prev, append(bytes.Clone(prev), randomBytes(r, 100)...)},
+		"truncated": {prev, prev[:1000]},
+		"tiny":      {[]byte("\x00\x01one"), []byte("\x00\x01two")},
+		"empty":     {prev, nil},
+	}
+	for name, c := range cases {
+		ops := computeDelta(c[0], c[1])
+		var got bytes.Buffer
+		if err := applyDelta(bytes.NewReader(c[0]), bufio.NewReader(bytes.NewReader(ops)), &got, int64(len(c[1]))); err != nil {
+			t.Fatalf("%s: applyDelta() failed: %v", name, err)
+		}
+		if !bytes.Equal(got.Bytes(), c[1]) {
+			t.Errorf("%s: delta did not rebuild the content", name)
+		}
+	}
+
+	if ops := computeDelta(prev, edited); len(ops) > 4<<10 {
+		t.Errorf("expected a small delta for a small edit, got %d bytes", len(ops))
+	}
+}
+
+func TestBinaryCommitChain(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(objectDir, 0o755); err != nil {
+		t.Fatalf("failed to create object directory: %v", err)
+	}
+	defer func(interval int) { SnapshotInterval = interval }(SnapshotInterval)
+	SnapshotInterval = 4
+
+	r := rand.New(rand.NewSource(2))
+	content := randomBytes(r, 64<<10)
+	if err := os.WriteFile("asset.bin", content, 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	objID, err := WriteObject("asset.bin", "", nil, content)
+	if err != nil {
+		t.Fatalf("WriteObject() failed: %v", err)
+	}
+
+	versions := map[string][]byte{objID: content}
+	objects := []string{objID}
+	for i := range 10 {
+		content = bytes.Clone(content)
+		copy(content[i*1000:], randomBytes(r, 100))
+		if err := os.WriteFile("asset.bin", content, 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if objID, err = CommitBinFile("asset.bin", objID); err != nil {
+			t.Fatalf("CommitBinFile() failed: %v", err)
+		}
+		versions[objID] = content
+		objects = append(objects, objID)
+	}
+	if _, err := CommitBinFile("asset.bin", objID); err == nil {
+		t.Error("expected an unchanged file not to be committed")
+	}
+
+	for i, id := range objects {
+		d, err := depth(id)
+		if err != nil {
+			t.Fatalf("depth() failed: %v", err)
+		}
+		if d != i%SnapshotInterval {
+			t.Errorf("commit %d: expected depth %d, got %d", i, i%SnapshotInterval, d)
+		}
+		got, err := ReadObject(id)
+		if err != nil || !bytes.Equal(got, versions[id]) {
+			t.Errorf("commit %d: expected the committed content, got %d bytes, %v", i, len(got), err)
+		}
+	}
+
+	if err := RevertBinFile("restored.bin", objects[6]); err != nil {
+		t.Fatalf("RevertBinFile() failed: %v", err)
+	}
+	if got, _ := os.ReadFile("restored.bin"); !bytes.Equal(got, versions[objects[6]]) {
+		t.Error("expected the reverted file to hold commit 6")
+	}
+
+	// Dependencies of the last commit reach back to the latest full copy
+	deps, err := WithDependencies(objectDir, objects[len(objects)-1:])
+	if err != nil {
+		t.Fatalf("WithDependencies() failed: %v", err)
+	}
+	if want := objects[8:]; !slices.Equal(deps, want) {
+		t.Errorf("expected dependencies %v, got %v", want, deps)
+	}
+}
+
+func TestLargeBinaryCommits(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(objectDir, 0o755); err != nil {
+		t.Fatalf("failed to create object directory: %v", err)
+	}
+	defer func(size int64) { MaxDeltaSize = size }(MaxDeltaSize)
+	MaxDeltaSize = 16 << 10
+
+	r := rand.New(rand.NewSource(3))
+	large := randomBytes(r, 32<<10)
+	objID, err := WriteObject("asset.bin", "", nil, large)
+	if err != nil {
+		t.Fatalf("WriteObject() failed: %v", err)
+	}
+	base := objID
+
+	// A small version of a large file and a large version are both stored as full copies
+	for _, content := range [][]byte{large[:1000], append(bytes.Clone(large), 1)} {
+		if err := os.WriteFile("asset.bin", content, 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if objID, err = CommitBinFile("asset.bin", objID); err != nil {
+			t.Fatalf("CommitBinFile() failed: %v", err)
+		}
+		if IsDelta(objID) {
+			t.Errorf("expected a full copy for %d bytes, got %s", len(content), objID)
+		}
+		if got, err := ReadObject(objID); err != nil || !bytes.Equal(got, content) {
+			t.Errorf("expected the committed content, got %d bytes, %v", len(got), err)
+		}
+	}
+
+	// Rebuilt versions are written inside the repository and removed afterwards
+	MaxDeltaSize = 64 << 10
+	changed := bytes.Clone(large)
+	changed[1000]++
+	delta, err := WriteObject("asset.bin", base, large, changed)
+	if err != nil || !IsDelta(delta) {
+		t.Fatalf("expected a delta, got %s, %v", delta, err)
+	}
+	object, err := Open(delta)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	temp, _ := filepath.Glob(".qwe/_tmp_bin_*")
+	object.Close()
+	if len(temp) != 1 {
+		t.Errorf("expected the rebuilt version in .qwe, got %v", temp)
+	}
+	if temp, _ = filepath.Glob(".qwe/_tmp_bin_*"); len(temp) != 0 {
+		t.Errorf("expected the rebuilt version to be removed, got %v", temp)
+	}
+}
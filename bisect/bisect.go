@@ -0,0 +1,346 @@
+package bisect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	rb "github.com/mainak55512/qwe/rebase"
+	res "github.com/mainak55512/qwe/reconstruct"
+	rv "github.com/mainak55512/qwe/revert"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+const (
+	bisectPath = ".qwe/_bisect.qwe"
+	unknown    = -1
+	skipCode   = 125 // exit code of a bisect script for commits that can not be tested
+)
+
+// State of a running bisect, commit ids are positions in Tracker.Versions or GroupTracker.VersionOrder
+type State struct {
+	Target   string `json:"target"`
+	IsGroup  bool   `json:"is_group"`
+	Original int    `json:"original"`
+	Count    int    `json:"count"`
+	Current  int    `json:"current"`
+	Good     int    `json:"good"`
+	Bad      int    `json:"bad"`
+	Skipped  []int  `json:"skipped"`
+}
+
+func load() (*State, error) {
+	if !utl.FileExists(bisectPath) {
+		return nil, er.BisectNotStarted
+	}
+	content, err := os.ReadFile(bisectPath)
+	if err != nil {
+		return nil, er.TrackerAccessErr
+	}
+	var state State
+	if err = json.Unmarshal(content, &state); err != nil {
+		return nil, er.TrackerParseErr
+	}
+	return &state, nil
+}
+
+func (s *State) save() error {
+	content, err := json.MarshalIndent(s, "", " ")
+	if err != nil {
+		return er.TrackerWriteErr
+	}
+	if err = os.WriteFile(bisectPath, content, 0644); err != nil {
+		return er.TrackerWriteErr
+	}
+	return nil
+}
+
+// Reverts the working tree to a commit of the bisected file or group
+func (s *State) checkout(commitID int) error {
+	if s.IsGroup {
+		return rv.RevertGroup(s.Target, commitID, false, false)
+	}
+	if commitID == res.BaseVersion {
+		return rb.Rebase(s.Target, false, false)
+	}
+	return rv.Revert(commitID, s.Target, false, false)
+}
+
+// Returns the files of the target that would be overwritten while bisecting and have uncommitted changes
+func modifiedFiles(target string, isGroup bool) ([]string, error) {
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return nil, err
+	}
+	filePaths := []string{target}
+	if isGroup {
+		_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+		if err != nil {
+			return nil, err
+		}
+		filePaths = nil
+		gr := groupTracker[utl.Hasher(target)]
+		for _, version := range gr.Versions {
+			for _, f := range version.Files {
+				filePaths = append(filePaths, f.FileName)
+			}
+		}
+	}
+
+	seen := make(map[string]struct{})
+	var modified []string
+	for _, filePath := range filePaths {
+		if _, ok := seen[filePath]; ok {
+			continue
+		}
+		seen[filePath] = struct{}{}
+		val, ok := tracker[utl.Hasher(filePath)]
+		if !ok {
+			continue
+		}
+		isModified, err := res.Modified(val, filePath)
+		if err != nil {
+			return nil, err
+		}
+		if isModified {
+			modified = append(modified, filePath)
+		}
+	}
+	sort.Strings(modified)
+	return modified, nil
+}
+
+// Starts bisecting the commits of a tracked file or group, optionally with known bad and good commits
+func Start(target string, bad, good int) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	if utl.FileExists(bisectPath) {
+		return er.BisectInProgress
+	}
+
+	state := &State{
+		Target: target,
+		Good:   unknown,
+		Bad:    unknown,
+	}
+
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return err
+	}
+	if val, ok := tracker[utl.Hasher(target)]; ok {
+		state.Count = len(val.Versions)
+		state.Original, _ = val.CommitID(val.Current)
+		state.Current = state.Original
+	} else {
+		_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+		if err != nil {
+			return err
+		}
+		gr, ok := groupTracker[utl.Hasher(target)]
+		if !ok {
+			return er.FileNotTracked
+		}
+		state.IsGroup = true
+		state.Count = len(gr.VersionOrder)
+		for i, k := range gr.VersionOrder {
+			if k == gr.Current {
+				state.Original = i
+			}
+		}
+		state.Current = state.Original
+	}
+
+	// Bisect checks out many versions, so the working copy must be clean before starting
+	modified, err := modifiedFiles(target, state.IsGroup)
+	if err != nil {
+		return err
+	}
+	if len(modified) > 0 {
+		return fmt.Errorf("%w: %s", er.UncommittedChanges, strings.Join(modified, ", "))
+	}
+
+	for _, commitID := range []int{bad, good} {
+		if commitID != unknown && (commitID < 0 || commitID > state.Count-1) {
+			return er.InvalidCommitNo
+		}
+	}
+	state.Bad = bad
+	state.Good = good
+	if err = state.save(); err != nil {
+		return err
+	}
+	fmt.Println("Started bisecting", target)
+	_, err = state.next()
+	return err
+}
+
+// Marks a commit as good or bad, the checked out commit is used if commitID is unknown
+func Mark(isGood bool, commitID int) error {
+	state, err := load()
+	if err != nil {
+		return err
+	}
+	if commitID == unknown {
+		commitID = state.Current
+	}
+	if commitID < 0 || commitID > state.Count-1 {
+		return er.InvalidCommitNo
+	}
+	if isGood {
+		state.Good = commitID
+	} else {
+		state.Bad = commitID
+	}
+	if err = state.save(); err != nil {
+		return err
+	}
+	_, err = state.next()
+	return err
+}
+
+// Marks the checked out commit as not testable and checks out another one
+func Skip() error {
+	state, err := load()
+	if err != nil {
+		return err
+	}
+	state.Skipped = append(state.Skipped, state.Current)
+	if err = state.save(); err != nil {
+		return err
+	}
+	_, err = state.next()
+	return err
+}
+
+// Returns the commits between the good and the bad commit that are left to test
+func (s *State) candidates() []int {
+	skipped := make(map[int]struct{})
+	for _, k := range s.Skipped {
+		skipped[k] = struct{}{}
+	}
+	var candidates []int
+	for i := s.Good + 1; i < s.Bad; i++ {
+		if _, ok := skipped[i]; !ok {
+			candidates = append(candidates, i)
+		}
+	}
+	return candidates
+}
+
+// Checks out the next commit to test, returns true when the first bad commit is found
+func (s *State) next() (bool, error) {
+	if s.Good == unknown || s.Bad == unknown {
+		if s.Good == unknown && s.Bad == unknown {
+			fmt.Println("Mark a good and a bad commit to continue")
+		} else if s.Good == unknown {
+			fmt.Println("Mark a good commit to continue")
+		} else {
+			fmt.Println("Mark a bad commit to continue")
+		}
+		return false, nil
+	}
+	if s.Good >= s.Bad {
+		return false, er.BisectBadRange
+	}
+
+	candidates := s.candidates()
+	if len(candidates) == 0 {
+		if untested := s.Bad - s.Good - 1; untested > 0 {
+			fmt.Printf("First bad commit is between %d and %d, %d skipped commits could not be tested\n", s.Good+1, s.Bad, untested)
+		} else {
+			fmt.Printf("Commit %d is the first bad commit of %s\n", s.Bad, s.Target)
+		}
+		return true, nil
+	}
+
+	s.Current = candidates[len(candidates)/2]
+	if err := s.save(); err != nil {
+		return false, err
+	}
+	if err := s.checkout(s.Current); err != nil {
+		return false, err
+	}
+	fmt.Printf("Bisecting: %d commits left to test, checked out commit %d\n", len(candidates)-1, s.Current)
+	return false, nil
+}
+
+// Tests commits with a script until the first bad commit is found.
+// Exit code 0 marks a commit good, 125 skips it, any other code up to 127 marks it bad.
+func Run(script string, args []string) error {
+	state, err := load()
+	if err != nil {
+		return err
+	}
+	if state.Good == unknown || state.Bad == unknown {
+		return er.BisectBadRange
+	}
+
+	// Nothing is left to test if the first bad commit was already found or the range is invalid
+	if state.Good >= state.Bad || len(state.candidates()) == 0 {
+		_, err = state.next()
+		return err
+	}
+
+	for {
+		cmd := exec.Command(script, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err := cmd.Run()
+
+		code := 0
+		if err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				return err
+			}
+			code = exitErr.ExitCode()
+		}
+
+		switch {
+		case code == 0:
+			state.Good = state.Current
+		case code == skipCode:
+			state.Skipped = append(state.Skipped, state.Current)
+		case code > 0 && code < 128:
+			state.Bad = state.Current
+		default:
+			return fmt.Errorf("Bisect script exited with code %d, stopping bisect run", code)
+		}
+		if err = state.save(); err != nil {
+			return err
+		}
+		done, err := state.next()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// Ends the bisect and checks out the commit that was checked out before it started
+func Reset() error {
+	state, err := load()
+	if err != nil {
+		return err
+	}
+	if state.Current != state.Original {
+		if err = state.checkout(state.Original); err != nil {
+			return err
+		}
+	}
+	if err = os.Remove(bisectPath); err != nil {
+		return err
+	}
+	fmt.Println("Bisect finished, restored", state.Target)
+	return nil
+}
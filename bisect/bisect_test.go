@@ -0,0 +1,230 @@
+package bisect
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	cm "github.com/mainak55512/qwe/commit"
+	in "github.com/mainak55512/qwe/initializer"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+func writeFile(t *testing.T, filePath, content string) {
+	t.Helper()
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+func readFile(t *testing.T, filePath string) string {
+	t.Helper()
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	return string(content)
+}
+
+// Commits eight versions of notes.txt, commit 5 introduces the bug
+func setupFile(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	writeFile(t, "notes.txt", "base\n")
+	if _, err := tr.StartTracking("notes.txt"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	for i := range 8 {
+		content := fmt.Sprintf("version %d\n", i)
+		if i >= 5 {
+			content += "bug\n"
+		}
+		writeFile(t, "notes.txt", content)
+		if _, _, err := cm.CommitUnit("notes.txt", content); err != nil {
+			t.Fatalf("failed to commit file: %v", err)
+		}
+	}
+}
+
+// Writes an executable script for Run
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("bisect scripts are shell scripts")
+	}
+	script := filepath.Join(t.TempDir(), "test.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	return script
+}
+
+func TestBisectFile(t *testing.T) {
+	setupFile(t)
+
+	if err := Mark(true, unknown); !errors.Is(err, er.BisectNotStarted) {
+		t.Errorf("expected BisectNotStarted, got %v", err)
+	}
+	if err := Start("notes.txt", 7, 8); !errors.Is(err, er.InvalidCommitNo) {
+		t.Errorf("expected InvalidCommitNo for a commit out of range, got %v", err)
+	}
+	if utl.FileExists(bisectPath) {
+		t.Fatal("a failed start left a bisect behind")
+	}
+
+	if err := Start("notes.txt", unknown, unknown); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if err := Start("notes.txt", unknown, unknown); !errors.Is(err, er.BisectInProgress) {
+		t.Errorf("expected BisectInProgress, got %v", err)
+	}
+	if err := Mark(false, 7); err != nil {
+		t.Fatalf("Mark() failed: %v", err)
+	}
+	if err := Mark(true, 0); err != nil {
+		t.Fatalf("Mark() failed: %v", err)
+	}
+
+	// Answer with the content of the checked out commit, skipping commit 4 once
+	skipped := false
+	for range 10 {
+		state, err := load()
+		if err != nil {
+			t.Fatalf("failed to load state: %v", err)
+		}
+		if len(state.candidates()) == 0 {
+			break
+		}
+		content := readFile(t, "notes.txt")
+		if !strings.HasPrefix(content, fmt.Sprintf("version %d\n", state.Current)) {
+			t.Fatalf("expected commit %d to be checked out, got %q", state.Current, content)
+		}
+		if state.Current == 4 && !skipped {
+			skipped = true
+			if err := Skip(); err != nil {
+				t.Fatalf("Skip() failed: %v", err)
+			}
+			continue
+		}
+		if err := Mark(!strings.Contains(content, "bug"), unknown); err != nil {
+			t.Fatalf("Mark() failed: %v", err)
+		}
+	}
+	state, err := load()
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	if state.Bad != 5 || state.Good != 3 {
+		t.Errorf("expected good 3 and bad 5 with commit 4 skipped, got good %d and bad %d", state.Good, state.Bad)
+	}
+
+	if err := Reset(); err != nil {
+		t.Fatalf("Reset() failed: %v", err)
+	}
+	if content := readFile(t, "notes.txt"); content != "version 7\nbug\n" {
+		t.Errorf("expected the original commit to be restored, got %q", content)
+	}
+	if utl.FileExists(bisectPath) {
+		t.Error("Reset() left the bisect state behind")
+	}
+	if err := Reset(); !errors.Is(err, er.BisectNotStarted) {
+		t.Errorf("expected BisectNotStarted after reset, got %v", err)
+	}
+}
+
+func TestBisectRunFile(t *testing.T) {
+	setupFile(t)
+	script := writeScript(t, `grep -q bug notes.txt && exit 1
+exit 0`)
+
+	if err := Start("notes.txt", unknown, 0); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if err := Run(script, nil); !errors.Is(err, er.BisectBadRange) {
+		t.Errorf("expected BisectBadRange without a bad commit, got %v", err)
+	}
+	if err := Mark(false, 7); err != nil {
+		t.Fatalf("Mark() failed: %v", err)
+	}
+	if err := Run(script, nil); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	state, _ := load()
+	if state.Bad != 5 || state.Good != 4 {
+		t.Errorf("expected commit 5 as first bad commit, got good %d and bad %d", state.Good, state.Bad)
+	}
+}
+
+func TestBisectRunGroup(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	writeFile(t, "a.txt", "a 0\n")
+	writeFile(t, "b.txt", "b 0\n")
+	if err := tr.StartGroupTracking("docs", []string{"a.txt", "b.txt"}); err != nil {
+		t.Fatalf("failed to track files in group: %v", err)
+	}
+
+	// Group commit 2 can not be tested, group commit 3 introduces the bug in b.txt
+	for i := 1; i <= 4; i++ {
+		a := fmt.Sprintf("a %d\n", i)
+		if i == 2 {
+			a += "broken\n"
+		}
+		b := fmt.Sprintf("b %d\n", i)
+		if i >= 3 {
+			b += "bug\n"
+		}
+		writeFile(t, "a.txt", a)
+		writeFile(t, "b.txt", b)
+		if err := cm.CommitGroup("docs", fmt.Sprintf("commit %d", i), false, false); err != nil {
+			t.Fatalf("failed to commit group: %v", err)
+		}
+	}
+
+	marker := filepath.Join(t.TempDir(), "runs")
+	script := writeScript(t, fmt.Sprintf(`echo run >> %q
+grep -q broken a.txt && exit 125
+grep -q bug b.txt && exit 1
+exit 0`, marker))
+
+	if err := Start("docs", 4, 0); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if err := Run(script, nil); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	state, _ := load()
+	if !state.IsGroup || state.Good != 1 || state.Bad != 3 || fmt.Sprint(state.Skipped) != "[2]" {
+		t.Errorf("expected good 1, bad 3 and commit 2 skipped, got %+v", state)
+	}
+	runs := strings.Count(readFile(t, marker), "run")
+
+	// A finished bisect does not run the script again
+	if err := Run(script, nil); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if again := strings.Count(readFile(t, marker), "run"); again != runs {
+		t.Errorf("expected no further script runs after the first bad commit was found, got %d more", again-runs)
+	}
+
+	if err := Reset(); err != nil {
+		t.Fatalf("Reset() failed: %v", err)
+	}
+	if a, b := readFile(t, "a.txt"), readFile(t, "b.txt"); a != "a 4\n" || b != "b 4\nbug\n" {
+		t.Errorf("expected the latest group commit to be restored, got %q and %q", a, b)
+	}
+}
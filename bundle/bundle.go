@@ -0,0 +1,261 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bh "github.com/mainak55512/qwe/binaryhandler"
+	cp "github.com/mainak55512/qwe/compressor"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+const (
+	bundleFormat  = "qwe-bundle"
+	bundleVersion = 1
+	manifestName  = "manifest.json"
+	objectPrefix  = "objects/"
+)
+
+// First entry of a bundle, describes its content
+type Manifest struct {
+	Format    string     `json:"format"`
+	Version   int        `json:"version"`
+	CreatedAt string     `json:"created_at"`
+	Author    string     `json:"author"`
+	Objects   []string   `json:"objects"`
+	History   tr.History `json:"history"`
+}
+
+// Writes the trackers and every object they refer to into a single bundle file.
+// If targets are supplied only those files and groups, with the files of the groups, are bundled.
+func Create(output string, targets []string) (err error) {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+
+	h, err := tr.LoadHistory(tr.QweDir)
+	if err != nil {
+		return err
+	}
+	if len(targets) > 0 {
+		if h, err = h.SelectTargets(targets); err != nil {
+			return err
+		}
+	}
+
+	// Binary deltas need the objects they are based on, even if no commit refers to them anymore
+	objects, err := bh.WithDependencies(filepath.Join(tr.QweDir, "_object"), h.Objects())
+	if err != nil {
+		return err
+	}
+	manifest := Manifest{
+		Format:    bundleFormat,
+		Version:   bundleVersion,
+		CreatedAt: time.Now().String()[:16],
+		Author:    utl.Author(),
+		Objects:   objects,
+		History:   h,
+	}
+	content, err := json.MarshalIndent(manifest, "", " ")
+	if err != nil {
+		return er.OutputWriteErr
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return er.OutputWriteErr
+	}
+	// Never leave a partial bundle behind
+	defer func() {
+		if closeErr := file.Close(); err == nil && closeErr != nil {
+			err = er.OutputWriteErr
+		}
+		if err != nil {
+			os.Remove(output)
+		}
+	}()
+
+	gw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gw)
+	if err = writeEntry(tw, manifestName, content); err != nil {
+		return err
+	}
+	for _, objID := range manifest.Objects {
+		content, err := os.ReadFile(filepath.Join(tr.QweDir, "_object", objID))
+		if err != nil {
+			return fmt.Errorf("%w: %s", er.MissingObject, objID)
+		}
+		if err = writeEntry(tw, objectPrefix+objID, content); err != nil {
+			return err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return er.OutputWriteErr
+	}
+	if err = gw.Close(); err != nil {
+		return er.OutputWriteErr
+	}
+
+	fmt.Printf("Bundled %d files, %d groups and %d objects to %s\n", len(h.Files), len(h.Groups), len(manifest.Objects), output)
+	return nil
+}
+
+func writeEntry(tw *tar.Writer, name string, content []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return er.OutputWriteErr
+	}
+	if _, err := tw.Write(content); err != nil {
+		return er.OutputWriteErr
+	}
+	return nil
+}
+
+// Reads and validates the manifest of a bundle
+func readManifest(r *tar.Reader) (*Manifest, error) {
+	header, err := r.Next()
+	if err != nil || header.Name != manifestName {
+		return nil, fmt.Errorf("%w: missing manifest", er.InvalidBundle)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", er.InvalidBundle, err)
+	}
+	var manifest Manifest
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: invalid manifest", er.InvalidBundle)
+	}
+	if manifest.Format != bundleFormat || manifest.Version != bundleVersion {
+		return nil, fmt.Errorf("%w: unsupported format %s version %d", er.InvalidBundle, manifest.Format, manifest.Version)
+	}
+	if manifest.History.Files == nil {
+		manifest.History.Files = make(tr.TrackerSchema)
+	}
+	if manifest.History.Groups == nil {
+		manifest.History.Groups = make(tr.GroupTrackerSchema)
+	}
+	if manifest.History.Paths == nil {
+		manifest.History.Paths = make(tr.TrackFiles)
+	}
+	tr.MigrateGroups(manifest.History.Groups, manifest.History.Files)
+
+	listed := make(map[string]struct{}, len(manifest.Objects))
+	for _, objID := range manifest.Objects {
+		if !tr.ValidObjectID(objID) {
+			return nil, fmt.Errorf("%w: invalid object %q", er.InvalidBundle, objID)
+		}
+		listed[objID] = struct{}{}
+	}
+	for _, objID := range manifest.History.Objects() {
+		if _, ok := listed[objID]; !ok {
+			return nil, fmt.Errorf("%w: object %s is not listed", er.InvalidBundle, objID)
+		}
+	}
+	return &manifest, nil
+}
+
+// Validates a bundle and merges its files and groups into the repository.
+// Histories that diverged from the local ones are reported as conflicts and left untouched.
+func Unbundle(input string) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+
+	file, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("%w: %v", er.InvalidBundle, err)
+	}
+	r := tar.NewReader(gr)
+
+	manifest, err := readManifest(r)
+	if err != nil {
+		return err
+	}
+
+	// Objects are staged first, nothing is changed in the repository until the whole bundle is validated
+	staging, err := os.MkdirTemp(tr.QweDir, "_bundle_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	pending := make(map[string]struct{}, len(manifest.Objects))
+	for _, objID := range manifest.Objects {
+		pending[objID] = struct{}{}
+	}
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", er.InvalidBundle, err)
+		}
+		objID := strings.TrimPrefix(header.Name, objectPrefix)
+		if _, ok := pending[objID]; !ok || !strings.HasPrefix(header.Name, objectPrefix) {
+			return fmt.Errorf("%w: unexpected entry %s", er.InvalidBundle, header.Name)
+		}
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("%w: %v", er.InvalidBundle, err)
+		}
+		if _, err = cp.DecompressBytes(content); err != nil {
+			return fmt.Errorf("%w: corrupted object %s", er.InvalidBundle, objID)
+		}
+		if err = os.WriteFile(filepath.Join(staging, objID), content, 0644); err != nil {
+			return err
+		}
+		delete(pending, objID)
+	}
+	for objID := range pending {
+		return fmt.Errorf("%w: missing object %s", er.InvalidBundle, objID)
+	}
+
+	local, err := tr.LoadHistory(tr.QweDir)
+	if err != nil {
+		return err
+	}
+	report, objects := local.Merge(manifest.History)
+	if objects, err = bh.WithDependencies(staging, objects); err != nil {
+		return fmt.Errorf("%w: %v", er.InvalidBundle, err)
+	}
+
+	// Objects are published before the trackers that refer to them
+	for _, objID := range objects {
+		target := filepath.Join(tr.QweDir, "_object", objID)
+		if utl.FileExists(target) {
+			continue
+		}
+		if err = os.Rename(filepath.Join(staging, objID), target); err != nil {
+			return err
+		}
+	}
+	if len(report.Added)+len(report.Updated) > 0 {
+		if err = local.Save(); err != nil {
+			return err
+		}
+	}
+
+	report.Print()
+	return nil
+}
@@ -0,0 +1,149 @@
+package bundle
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bh "github.com/mainak55512/qwe/binaryhandler"
+	hs "github.com/mainak55512/qwe/history"
+	in "github.com/mainak55512/qwe/initializer"
+	qt "github.com/mainak55512/qwe/qwetest"
+	utl "github.com/mainak55512/qwe/qweutils"
+	res "github.com/mainak55512/qwe/reconstruct"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+func setupRepo(t *testing.T, dir string) {
+	t.Helper()
+	t.Chdir(dir)
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+}
+
+func TestCreateAndUnbundle(t *testing.T) {
+	source, dest := t.TempDir(), t.TempDir()
+	bundleOne := filepath.Join(t.TempDir(), "one.qweb")
+	bundleTwo := filepath.Join(t.TempDir(), "two.qweb")
+
+	setupRepo(t, source)
+	for _, filePath := range []string{"notes.txt", "other.txt"} {
+		if err := os.WriteFile(filePath, []byte("base\n"), 0o644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+		if _, err := tr.StartTracking(filePath); err != nil {
+			t.Fatalf("failed to track file: %v", err)
+		}
+	}
+	qt.CommitFile(t, "notes.txt", "one\n", "First")
+	if err := Create(bundleOne, []string{"notes.txt"}); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	qt.CommitFile(t, "notes.txt", "two\n", "Second")
+	if err := Create(bundleTwo, nil); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	if err := Create(bundleTwo, []string{"missing.txt"}); err == nil {
+		t.Error("expected error for untracked target")
+	}
+
+	setupRepo(t, dest)
+	if err := Unbundle(bundleOne); err != nil {
+		t.Fatalf("Unbundle() failed: %v", err)
+	}
+	h, err := tr.LoadHistory(tr.QweDir)
+	if err != nil {
+		t.Fatalf("failed to load history: %v", err)
+	}
+	if len(h.Files) != 1 || len(h.Files[utl.Hasher("notes.txt")].Versions) != 1 {
+		t.Fatalf("expected only notes.txt with one commit, got %+v", h.Files)
+	}
+
+	// The second bundle fast-forwards notes.txt and adds other.txt
+	if err := Unbundle(bundleTwo); err != nil {
+		t.Fatalf("Unbundle() failed: %v", err)
+	}
+	h, err = tr.LoadHistory(tr.QweDir)
+	if err != nil {
+		t.Fatalf("failed to load history: %v", err)
+	}
+	val := h.Files[utl.Hasher("notes.txt")]
+	if len(h.Files) != 2 || len(val.Versions) != 2 {
+		t.Fatalf("expected two files and two commits of notes.txt, got %+v", h.Files)
+	}
+	for _, objID := range h.Objects() {
+		if !utl.FileExists(filepath.Join(tr.QweDir, "_object", objID)) {
+			t.Errorf("object %s was not copied", objID)
+		}
+	}
+
+	// Diverged history is reported and the local one kept
+	if err := tr.StopTracking("notes.txt"); err != nil {
+		t.Fatalf("failed to untrack file: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte("local\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := tr.StartTracking("notes.txt"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	local, _ := tr.LoadHistory(tr.QweDir)
+	report, _ := local.Merge(h)
+	if len(report.Conflicts) != 1 || report.Conflicts[0] != "notes.txt" {
+		t.Errorf("expected notes.txt to conflict, got %+v", report)
+	}
+
+	if err := os.WriteFile("broken.qweb", []byte("not a bundle"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := Unbundle("broken.qweb"); err == nil {
+		t.Error("expected error for invalid bundle")
+	}
+}
+
+func TestBundleBinaryDeltas(t *testing.T) {
+	source, dest := t.TempDir(), t.TempDir()
+	bundlePath := filepath.Join(t.TempDir(), "assets.qweb")
+
+	setupRepo(t, source)
+	content := append([]byte{0, 1}, bytes.Repeat([]byte("0123456789abcdef"), 1024)...)
+	if err := os.WriteFile("asset.bin", content, 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := tr.StartTracking("asset.bin"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	var versions []string
+	for _, edit := range []string{"one", "two", "three"} {
+		content = append(bytes.Clone(content), edit...)
+		versions = append(versions, string(content))
+		qt.CommitFile(t, "asset.bin", string(content), edit)
+	}
+
+	// After pruning, the base and the commits are deltas against objects no commit refers to
+	if err := hs.Prune("asset.bin", 1); err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	h, _ := tr.LoadHistory(tr.QweDir)
+	if val := h.Files[utl.Hasher("asset.bin")]; !bh.IsDelta(val.Base) {
+		t.Fatalf("expected the pruned base to be a delta, got %s", val.Base)
+	}
+	if err := Create(bundlePath, nil); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+
+	setupRepo(t, dest)
+	if err := Unbundle(bundlePath); err != nil {
+		t.Fatalf("Unbundle() failed: %v", err)
+	}
+	h, _ = tr.LoadHistory(tr.QweDir)
+	val := h.Files[utl.Hasher("asset.bin")]
+	for commitID, want := range map[int]string{res.BaseVersion: versions[1], 0: versions[2]} {
+		got, err := res.Content(val, commitID)
+		if err != nil || string(got) != want {
+			t.Errorf("commit %d: expected the committed content, got %d bytes, %v", commitID, len(got), err)
+		}
+	}
+}
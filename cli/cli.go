@@ -3,17 +3,32 @@ package cli
 import (
 	"fmt"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	tw "text/tabwriter"
+	"time"
 
+	ar "github.com/mainak55512/qwe/archive"
+	bs "github.com/mainak55512/qwe/bisect"
+	bd "github.com/mainak55512/qwe/bundle"
 	cm "github.com/mainak55512/qwe/commit"
+	cf "github.com/mainak55512/qwe/config"
 	"github.com/mainak55512/qwe/diff"
+	gb "github.com/mainak55512/qwe/gitbridge"
+	gr "github.com/mainak55512/qwe/grep"
+	hs "github.com/mainak55512/qwe/history"
 	in "github.com/mainak55512/qwe/initializer"
 	er "github.com/mainak55512/qwe/qwerror"
 	rb "github.com/mainak55512/qwe/rebase"
 	rc "github.com/mainak55512/qwe/recover"
+	rm "github.com/mainak55512/qwe/remote"
 	rv "github.com/mainak55512/qwe/revert"
+	sv "github.com/mainak55512/qwe/server"
+	st "github.com/mainak55512/qwe/stash"
 	tr "github.com/mainak55512/qwe/tracker"
+	wt "github.com/mainak55512/qwe/watch"
 )
 
 /*
@@ -79,18 +94,63 @@ func helpText() {
 	fmt.Fprintln(w, "qwe track <file-path>\t[Start tracking a file]")
 	fmt.Fprintln(w, "qwe untrack <file-path>\t[Stop tracking a file individually and in all groups]")
 	fmt.Fprintln(w, "qwe group-track <group name> <file/folder-path>...\t[Start tracking one or more files in a group or all files of a folder in a group]")
+	fmt.Fprintln(w, "qwe group-untrack <group name> <file-path>\t[Leave a file out of the next commit of the group]")
+	fmt.Fprintln(w, "qwe group-rename <group name> <new group name>\t[Rename a group]")
+	fmt.Fprintln(w, "qwe group-delete <group name> [--purge]\t[Delete a group, --purge also untracks its files that are not part of other groups]")
 	fmt.Fprintln(w, "qwe list <file-path>\t[Get list of all commits on the file]")
 	fmt.Fprintln(w, "qwe group-list <group name>\t[Get list of all commits on the group]")
+	fmt.Fprintln(w, "qwe log [--path <prefix>] [--group <name>] [--since <date>] [--until <date>] [--grep <pattern>] [--author <name>] [--oneline]\t[Get list of commits of all files and groups in the repository]")
 	fmt.Fprintln(w, "qwe commit <file-path> \"<commit message>\"\t[Commit current version of the file to the version control]")
 	fmt.Fprintln(w, "qwe group-commit <group name> \"<commit message>\"\t[Commit current version of all the files tracked in the group]")
+	fmt.Fprintln(w, "qwe group-commit <group name> \"<commit message>\" --dry-run\t[List the files the group commit would record with their changed lines]")
+	fmt.Fprintln(w, "qwe group-commit <group name> \"<commit message>\" --allow-empty\t[Record a group commit even if no file changed]")
 	fmt.Fprintln(w, "qwe revert <file-path>\t[Revert the file to the last committed version]")
 	fmt.Fprintln(w, "qwe revert <file-path> <commit-id>\t[Revert the file to a previous version]")
+	fmt.Fprintln(w, "qwe revert <file-path> [commit-id] --stash\t[Stash uncommitted changes of the file before reverting]")
+	fmt.Fprintln(w, "qwe revert <file-path> [commit-id] --force\t[Revert the file discarding its uncommitted changes]")
 	fmt.Fprintln(w, "qwe group-revert <group name> <commit-id>\t[Revert all the files tracked in the group to a previous version]")
+	fmt.Fprintln(w, "qwe group-revert <group name> <commit-id> --stash\t[Stash uncommitted changes of the group before reverting]")
+	fmt.Fprintln(w, "qwe group-revert <group name> <commit-id> --force\t[Revert the group discarding uncommitted changes of its files]")
+	fmt.Fprintln(w, "qwe group-status <group name>\t[Get the files of the group that differ from the current group commit]")
+	fmt.Fprintln(w, "qwe group-diff <group name>\t[Get uncommitted changes of the files of the group]")
+	fmt.Fprintln(w, "qwe group-diff <group name> <commit-id>\t[Get changes of the working files since a commit of the group]")
+	fmt.Fprintln(w, "qwe group-diff <group name> <commit-id-1> <commit-id-2>\t[Get changes of the files between two commits of the group]")
+	fmt.Fprintln(w, "qwe archive <group name> <commit-id> -o <file.tar.gz/.tar/.zip>\t[Write the files of a group version to an archive]")
+	fmt.Fprintln(w, "qwe stash <file-path/group name> [\"<message>\"]\t[Save uncommitted changes of a file or group and restore the checked out version]")
+	fmt.Fprintln(w, "qwe stash list\t[Get list of all stashes]")
+	fmt.Fprintln(w, "qwe stash pop [stash-id]\t[Restore the latest or a specific stash and remove it]")
+	fmt.Fprintln(w, "qwe stash drop [stash-id]\t[Remove the latest or a specific stash]")
+	fmt.Fprintln(w, "qwe grep <pattern> [file-path]\t[Search all committed versions of tracked text files]")
+	fmt.Fprintln(w, "qwe grep -S <text> [file-path]\t[Get list of commits that changed the number of occurrences of the text]")
+	fmt.Fprintln(w, "qwe bisect start <file-path/group name> [bad-commit-id] [good-commit-id]\t[Start binary search for the commit that introduced a problem]")
+	fmt.Fprintln(w, "qwe bisect good [commit-id]\t[Mark the checked out or a specific commit as good]")
+	fmt.Fprintln(w, "qwe bisect bad [commit-id]\t[Mark the checked out or a specific commit as bad]")
+	fmt.Fprintln(w, "qwe bisect skip\t[Skip the checked out commit if it can not be tested]")
+	fmt.Fprintln(w, "qwe bisect run <script> [arguments]...\t[Test commits with a script, exit code 0 is good, 125 is skip, others are bad]")
+	fmt.Fprintln(w, "qwe bisect reset\t[End bisect and restore the version checked out before it]")
+	fmt.Fprintln(w, "qwe export-git <file-path/group name> [-o <output file>] [--branch <name>]\t[Write the history as a git fast-import stream]")
+	fmt.Fprintln(w, "qwe import-git <file-path> [-i <stream file>] [--path <path in git>] [--force]\t[Replay the git history of a file from a git fast-export stream]")
+	fmt.Fprintln(w, "qwe bundle create <bundle file> [file-path/group name...]\t[Write the history of the repository or of some files and groups to a bundle file]")
+	fmt.Fprintln(w, "qwe bundle unbundle <bundle file>\t[Validate a bundle and merge its history into the repository]")
+	fmt.Fprintln(w, "qwe remote add <name> <path> [--init]\t[Add another qwe repository as a remote, --init creates an empty store]")
+	fmt.Fprintln(w, "qwe remote remove <name>\t[Remove a remote]")
+	fmt.Fprintln(w, "qwe remote list\t[List the remotes]")
+	fmt.Fprintln(w, "qwe compression [codec] [--large <codec>] [--large-size <size>]\t[Show or set the codecs of new objects: zlib, gzip, zstd or none]")
+	fmt.Fprintln(w, "qwe push <remote> [file-path/group name...]\t[Send new commits to a remote]")
+	fmt.Fprintln(w, "qwe pull <remote> [file-path/group name...]\t[Fetch new commits from a remote]")
+	fmt.Fprintln(w, "qwe serve [--addr <host:port>] [--writable]\t[Browse the history in a web browser or through a JSON API]")
+	fmt.Fprintln(w, "qwe squash <file-path> <from-commit-id> <to-commit-id> [\"<message>\"]\t[Replace a range of commits with a single commit]")
+	fmt.Fprintln(w, "qwe prune <file-path> --keep <N>\t[Fold all but the latest N commits into the base version]")
+	fmt.Fprintln(w, "qwe prune <file-path> --older-than <YYYY-MM-DD[ HH:MM]>\t[Fold commits older than the date into the base version]")
+	fmt.Fprintln(w, "qwe watch [file-path/group name...] [--interval 2s] [--debounce 3s] [--min-gap 1m]\t[Commit tracked files and groups automatically after they are saved]")
+	fmt.Fprintln(w, "qwe watch squash <file-path/group name>\t[Squash consecutive automatic commits into one commit]")
+	fmt.Fprintln(w, "qwe reflog\t[Get list of all commands that changed the tracker state]")
+	fmt.Fprintln(w, "qwe undo\t[Restore the tracker state from before the latest command]")
 	fmt.Fprintln(w, "qwe current <file-path>\t[Get current commit details of the file]")
 	fmt.Fprintln(w, "qwe group-current <group name>\t[Get current commit details of the group]")
 	fmt.Fprintln(w, "qwe group-current <group name> <commit-id>\t[Get commit details of a specific commit of the group]")
 	fmt.Fprintln(w, "qwe recover <file-path>\t[Restore deleted file if earlier tracked]")
-	fmt.Fprintln(w, "qwe rebase <file-path>\t[Revert back to base version of the file]")
+	fmt.Fprintln(w, "qwe rebase <file-path> [--stash|--force]\t[Revert back to base version of the file]")
 	fmt.Fprintln(w, "qwe diff <file-path>\t[Shows difference between latest uncommitted version and latest committed version]")
 	fmt.Fprintln(w, "qwe diff <file-path> <commit-id-1> <commit-id-2>\t[Shows difference between two commits]")
 	fmt.Fprintln(w, "qwe diff <file-path> uncommitted <commit-id>\t[Shows difference between latest uncommitted version and commit-id version]")
@@ -98,15 +158,45 @@ func helpText() {
 	w.Flush()
 }
 
+// Reports whether a command writes its tracker changes as they are made
+func writeThrough(command_list []string) bool {
+	switch command_list[0] {
+	case "watch", "serve":
+		return true
+	case "bisect":
+		return len(command_list) > 1 && command_list[1] == "run"
+	}
+	return false
+}
+
 /*
 Handles command line arguments like init, track, commit, revert etc.
 */
-func HandleArgs() error {
+func HandleArgs() (err error) {
 	command_list := os.Args[1:]
 
 	if len(command_list) == 0 {
 		helpText()
 	} else {
+		tr.SetReflogCommand(strings.Join(command_list, " "))
+
+		// Trackers are written once when the command ends, except for commands that keep running
+		// or run other programs that may read the repository
+		if !writeThrough(command_list) {
+			// A closed pipe, e.g. 'qwe undo | head -1', must not end the command before the trackers are written
+			signal.Ignore(syscall.SIGPIPE)
+			tr.DeferWrites()
+			defer func() {
+				if flushErr := tr.FlushSession(); flushErr != nil && err == nil {
+					err = flushErr
+				}
+			}()
+		}
+
+		// Objects written by the command use the codecs configured for the repository
+		if err := cf.ApplyCompression(); err != nil {
+			return err
+		}
 
 		switch command_list[0] {
 		case "init":
@@ -170,6 +260,40 @@ func HandleArgs() error {
 					}
 				}
 			}
+		case "group-untrack":
+			{
+				if len(command_list) != 3 {
+					return er.CLIGrpUntrackErr
+				}
+				if err := tr.StopGroupTracking(command_list[1], command_list[2]); err != nil {
+					return err
+				}
+			}
+		case "group-rename":
+			{
+				if len(command_list) != 3 {
+					return er.CLIGrpRenameErr
+				}
+				if err := tr.RenameGroup(command_list[1], command_list[2]); err != nil {
+					return err
+				}
+			}
+		case "group-delete":
+			{
+				args, flags, err := parseFlags(command_list, []string{"purge"})
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "purge") {
+					return er.CLIGrpDeleteErr
+				}
+				if len(args) != 2 {
+					return er.CLIGrpDeleteErr
+				}
+				if err := tr.DeleteGroup(args[1], flags["purge"] != ""); err != nil {
+					return err
+				}
+			}
 		case "group-track":
 			{
 				if len(command_list) < 3 {
@@ -190,10 +314,17 @@ func HandleArgs() error {
 			}
 		case "group-commit":
 			{
-				if len(command_list) != 3 {
+				args, flags, err := parseFlags(command_list, []string{"allow-empty", "dry-run"})
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "allow-empty", "dry-run") {
+					return er.CLIGrpCommitErr
+				}
+				if len(args) != 3 {
 					return er.CLIGrpCommitErr
 				}
-				if err := cm.CommitGroup(command_list[1], command_list[2]); err != nil {
+				if err := cm.CommitGroup(args[1], args[2], flags["allow-empty"] != "", flags["dry-run"] != ""); err != nil {
 					return err
 				}
 			}
@@ -217,36 +348,117 @@ func HandleArgs() error {
 			}
 		case "revert":
 			{
-				if len(command_list) != 3 && len(command_list) != 2 {
+				args, flags, err := parseFlags(command_list, []string{"stash", "force"})
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "stash", "force") {
+					return er.CLIRevertErr
+				}
+				if len(args) != 3 && len(args) != 2 {
 					return er.CLIRevertErr
 				}
 				var commitNumber int
-				var err error
-				if len(command_list) == 3 {
-					commitNumber, err = strconv.Atoi(command_list[2])
+				if len(args) == 3 {
+					commitNumber, err = strconv.Atoi(args[2])
 					if err != nil {
 						return er.InvalidCommitNo
 					}
 				} else {
 					commitNumber = -1
 				}
-				if err := rv.Revert(commitNumber, command_list[1]); err != nil {
+				if err := rv.Revert(commitNumber, args[1], flags["stash"] != "", flags["force"] != ""); err != nil {
 					return err
 				}
 			}
 		case "group-revert":
 			{
-				if len(command_list) != 3 {
+				args, flags, err := parseFlags(command_list, []string{"stash", "force"})
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "stash", "force") {
+					return er.CLIGrpRevertErr
+				}
+				if len(args) != 3 {
 					return er.CLIGrpRevertErr
 				}
-				commitNumber, err := strconv.Atoi(command_list[2])
+				commitNumber, err := strconv.Atoi(args[2])
 				if err != nil {
 					return er.InvalidCommitNo
 				}
-				if err := rv.RevertGroup(command_list[1], commitNumber); err != nil {
+				if err := rv.RevertGroup(args[1], commitNumber, flags["stash"] != "", flags["force"] != ""); err != nil {
 					return err
 				}
 			}
+		case "archive":
+			{
+				args, flags, err := parseFlags(command_list, nil, "o", "output")
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "o", "output") {
+					return er.CLIArchiveErr
+				}
+				output := flags["o"]
+				if output == "" {
+					output = flags["output"]
+				}
+				if len(args) != 3 || output == "" {
+					return er.CLIArchiveErr
+				}
+				commitNumber, err := strconv.Atoi(args[2])
+				if err != nil {
+					return er.InvalidCommitNo
+				}
+				if err := ar.Create(args[1], commitNumber, output); err != nil {
+					return err
+				}
+			}
+		case "stash":
+			{
+				args, flags, err := parseFlags(command_list, nil)
+				if err != nil || !knownFlags(flags) {
+					return er.CLIStashErr
+				}
+				if len(args) < 2 || len(args) > 3 {
+					return er.CLIStashErr
+				}
+				switch args[1] {
+				case "list":
+					if len(args) != 2 {
+						return er.CLIStashErr
+					}
+					if err := st.List(); err != nil {
+						return err
+					}
+				case "pop", "drop":
+					stashID := 0
+					if len(args) == 3 {
+						stashID, err = strconv.Atoi(args[2])
+						if err != nil {
+							return er.InvalidStash
+						}
+					}
+					if args[1] == "pop" {
+						if err := st.Pop(stashID); err != nil {
+							return err
+						}
+					} else {
+						if err := st.Drop(stashID); err != nil {
+							return err
+						}
+					}
+				default:
+					message := ""
+					if len(args) == 3 {
+						message = args[2]
+					}
+					if err := st.Push(args[1], message); err != nil {
+						return err
+					}
+				}
+			}
 		case "diff":
 			{
 				if len(command_list) != 2 && len(command_list) != 4 {
@@ -261,6 +473,32 @@ func HandleArgs() error {
 					}
 				}
 			}
+		case "group-status":
+			{
+				if len(command_list) != 2 {
+					return er.CLIGrpStatusErr
+				}
+				if err := diff.GroupStatus(command_list[1]); err != nil {
+					return err
+				}
+			}
+		case "group-diff":
+			{
+				if len(command_list) < 2 || len(command_list) > 4 {
+					return er.CLIGroupDiffErr
+				}
+				ids := []int{-1, -1}
+				for i, arg := range command_list[2:] {
+					id, err := strconv.Atoi(arg)
+					if err != nil {
+						return er.InvalidCommitNo
+					}
+					ids[i] = id
+				}
+				if err := diff.GroupDiff(command_list[1], ids[0], ids[1], len(command_list) < 4); err != nil {
+					return err
+				}
+			}
 		case "current":
 			{
 				if len(command_list) != 2 {
@@ -300,10 +538,420 @@ func HandleArgs() error {
 			}
 		case "rebase":
 			{
-				if len(command_list) != 2 {
+				args, flags, err := parseFlags(command_list, []string{"stash", "force"})
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "stash", "force") {
 					return er.CLIRebaseErr
 				}
-				if err := rb.Rebase(command_list[1]); err != nil {
+				if len(args) != 2 {
+					return er.CLIRebaseErr
+				}
+				if err := rb.Rebase(args[1], flags["stash"] != "", flags["force"] != ""); err != nil {
+					return err
+				}
+			}
+		case "log":
+			{
+				args, flags, err := parseFlags(command_list, []string{"oneline"}, "path", "group", "since", "until", "grep", "author")
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "path", "group", "since", "until", "grep", "author", "oneline") {
+					return er.CLILogErr
+				}
+				if len(args) != 1 {
+					return er.CLILogErr
+				}
+				filter := cm.LogFilter{
+					PathPrefix: flags["path"],
+					Group:      flags["group"],
+					Author:     flags["author"],
+				}
+				for name := range flags {
+					switch name {
+					case "path", "group", "since", "until", "grep", "author", "oneline":
+					default:
+						return er.CLILogErr
+					}
+				}
+				if flags["since"] != "" {
+					if filter.Since, err = cm.ParseLogDate(flags["since"], false); err != nil {
+						return err
+					}
+				}
+				if flags["until"] != "" {
+					if filter.Until, err = cm.ParseLogDate(flags["until"], true); err != nil {
+						return err
+					}
+				}
+				if flags["grep"] != "" {
+					filter.Message = cm.MessagePattern(flags["grep"])
+				}
+				if err := cm.PrintLog(filter, flags["oneline"] != ""); err != nil {
+					return err
+				}
+			}
+		case "grep":
+			{
+				args, flags, err := parseFlags(command_list, nil, "S")
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "S") {
+					return er.CLIGrepErr
+				}
+				if text, ok := flags["S"]; ok {
+					if len(args) != 1 && len(args) != 2 {
+						return er.CLIGrepErr
+					}
+					filePath := ""
+					if len(args) == 2 {
+						filePath = args[1]
+					}
+					if err := gr.Pickaxe(text, filePath); err != nil {
+						return err
+					}
+				} else {
+					if len(args) != 2 && len(args) != 3 {
+						return er.CLIGrepErr
+					}
+					filePath := ""
+					if len(args) == 3 {
+						filePath = args[2]
+					}
+					if err := gr.Grep(args[1], filePath); err != nil {
+						return err
+					}
+				}
+			}
+		case "bisect":
+			{
+				if len(command_list) < 2 {
+					return er.CLIBisectErr
+				}
+				switch command_list[1] {
+				case "start":
+					if len(command_list) < 3 || len(command_list) > 5 {
+						return er.CLIBisectErr
+					}
+					commitIDs := []int{-1, -1}
+					for i, arg := range command_list[3:] {
+						commitID, err := strconv.Atoi(arg)
+						if err != nil {
+							return er.InvalidCommitNo
+						}
+						commitIDs[i] = commitID
+					}
+					if err := bs.Start(command_list[2], commitIDs[0], commitIDs[1]); err != nil {
+						return err
+					}
+				case "good", "bad":
+					if len(command_list) > 3 {
+						return er.CLIBisectErr
+					}
+					commitID := -1
+					if len(command_list) == 3 {
+						var err error
+						commitID, err = strconv.Atoi(command_list[2])
+						if err != nil {
+							return er.InvalidCommitNo
+						}
+					}
+					if err := bs.Mark(command_list[1] == "good", commitID); err != nil {
+						return err
+					}
+				case "skip":
+					if len(command_list) != 2 {
+						return er.CLIBisectErr
+					}
+					if err := bs.Skip(); err != nil {
+						return err
+					}
+				case "run":
+					if len(command_list) < 3 {
+						return er.CLIBisectErr
+					}
+					if err := bs.Run(command_list[2], command_list[3:]); err != nil {
+						return err
+					}
+				case "reset":
+					if len(command_list) != 2 {
+						return er.CLIBisectErr
+					}
+					if err := bs.Reset(); err != nil {
+						return err
+					}
+				default:
+					return er.CLIBisectErr
+				}
+			}
+		case "export-git":
+			{
+				args, flags, err := parseFlags(command_list, nil, "o", "output", "branch")
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "o", "output", "branch") {
+					return er.CLIExportGitErr
+				}
+				if len(args) != 2 {
+					return er.CLIExportGitErr
+				}
+				output := flags["o"]
+				if output == "" {
+					output = flags["output"]
+				}
+				if err := gb.ExportGit(args[1], output, flags["branch"]); err != nil {
+					return err
+				}
+			}
+		case "import-git":
+			{
+				args, flags, err := parseFlags(command_list, []string{"force"}, "i", "input", "path")
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "i", "input", "path", "force") {
+					return er.CLIImportGitErr
+				}
+				if len(args) != 2 {
+					return er.CLIImportGitErr
+				}
+				input := flags["i"]
+				if input == "" {
+					input = flags["input"]
+				}
+				if err := gb.ImportGit(args[1], input, flags["path"], flags["force"] != ""); err != nil {
+					return err
+				}
+			}
+		case "bundle":
+			{
+				if len(command_list) < 3 {
+					return er.CLIBundleErr
+				}
+				switch command_list[1] {
+				case "create":
+					if err := bd.Create(command_list[2], command_list[3:]); err != nil {
+						return err
+					}
+				case "unbundle":
+					if len(command_list) != 3 {
+						return er.CLIBundleErr
+					}
+					if err := bd.Unbundle(command_list[2]); err != nil {
+						return err
+					}
+				default:
+					return er.CLIBundleErr
+				}
+			}
+		case "remote":
+			{
+				args, flags, err := parseFlags(command_list, []string{"init"})
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "init") {
+					return er.CLIRemoteErr
+				}
+				if len(args) < 2 {
+					return er.CLIRemoteErr
+				}
+				switch {
+				case args[1] == "add" && len(args) == 4:
+					if err := rm.Add(args[2], args[3], flags["init"] != ""); err != nil {
+						return err
+					}
+				case args[1] == "remove" && len(args) == 3:
+					if err := rm.Remove(args[2]); err != nil {
+						return err
+					}
+				case args[1] == "list" && len(args) == 2:
+					if err := rm.List(); err != nil {
+						return err
+					}
+				default:
+					return er.CLIRemoteErr
+				}
+			}
+		case "compression":
+			{
+				args, flags, err := parseFlags(command_list, nil, "large", "large-size")
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "large", "large-size") {
+					return er.CLICompressionErr
+				}
+				if len(args) > 2 {
+					return er.CLICompressionErr
+				}
+				if len(args) == 1 && len(flags) == 0 {
+					if err := cf.PrintCompression(); err != nil {
+						return err
+					}
+					break
+				}
+				codec := ""
+				if len(args) == 2 {
+					codec = args[1]
+				}
+				largeSize := int64(-1)
+				if size, ok := flags["large-size"]; ok {
+					if largeSize, err = cf.ParseSize(size); err != nil {
+						return err
+					}
+				}
+				if err := cf.SetCompression(codec, flags["large"], largeSize); err != nil {
+					return err
+				}
+			}
+		case "push":
+			{
+				if len(command_list) < 2 {
+					return er.CLIPushErr
+				}
+				if err := rm.Push(command_list[1], command_list[2:]); err != nil {
+					return err
+				}
+			}
+		case "pull":
+			{
+				if len(command_list) < 2 {
+					return er.CLIPullErr
+				}
+				if err := rm.Pull(command_list[1], command_list[2:]); err != nil {
+					return err
+				}
+			}
+		case "serve":
+			{
+				args, flags, err := parseFlags(command_list, []string{"writable"}, "addr")
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "addr", "writable") {
+					return er.CLIServeErr
+				}
+				if len(args) != 1 {
+					return er.CLIServeErr
+				}
+				addr := flags["addr"]
+				if addr == "" {
+					addr = "127.0.0.1:8080"
+				}
+				if err := sv.Serve(addr, flags["writable"] != ""); err != nil {
+					return err
+				}
+			}
+		case "squash":
+			{
+				if len(command_list) != 4 && len(command_list) != 5 {
+					return er.CLISquashErr
+				}
+				from, err := strconv.Atoi(command_list[2])
+				if err != nil {
+					return er.CLISquashErr
+				}
+				to, err := strconv.Atoi(command_list[3])
+				if err != nil {
+					return er.CLISquashErr
+				}
+				var message string
+				if len(command_list) == 5 {
+					message = command_list[4]
+				} else if message, err = hs.SquashMessage(command_list[1], from, to); err != nil {
+					return err
+				}
+				if err := hs.Squash(command_list[1], from, to, message); err != nil {
+					return err
+				}
+			}
+		case "prune":
+			{
+				args, flags, err := parseFlags(command_list, nil, "keep", "older-than")
+				if err != nil {
+					return err
+				}
+				if !knownFlags(flags, "keep", "older-than") {
+					return er.CLIPruneErr
+				}
+				if len(args) != 2 || len(flags) != 1 {
+					return er.CLIPruneErr
+				}
+				switch {
+				case flags["keep"] != "":
+					keep, err := strconv.Atoi(flags["keep"])
+					if err != nil || keep < 0 {
+						return er.CLIPruneErr
+					}
+					if err := hs.Prune(args[1], keep); err != nil {
+						return err
+					}
+				case flags["older-than"] != "":
+					before, err := cm.ParseLogDate(flags["older-than"], false)
+					if err != nil {
+						return err
+					}
+					if err := hs.PruneBefore(args[1], before); err != nil {
+						return err
+					}
+				default:
+					return er.CLIPruneErr
+				}
+			}
+		case "watch":
+			{
+				args, flags, err := parseFlags(command_list, nil, "interval", "debounce", "min-gap")
+				if err != nil {
+					return err
+				}
+				if len(args) > 1 && args[1] == "squash" {
+					if len(args) != 3 || len(flags) > 0 {
+						return er.CLIWatchErr
+					}
+					if err := wt.SquashAuto(args[2]); err != nil {
+						return err
+					}
+					break
+				}
+				options := wt.DefaultOptions
+				durations := map[string]*time.Duration{
+					"interval": &options.Interval,
+					"debounce": &options.Debounce,
+					"min-gap":  &options.MinGap,
+				}
+				for name, value := range flags {
+					target, ok := durations[name]
+					if !ok {
+						return er.CLIWatchErr
+					}
+					if *target, err = time.ParseDuration(value); err != nil || *target < 0 {
+						return er.CLIWatchErr
+					}
+				}
+				if err := wt.Watch(args[1:], options); err != nil {
+					return err
+				}
+			}
+		case "reflog":
+			{
+				if len(command_list) != 1 {
+					return er.CLIReflogErr
+				}
+				if err := tr.PrintReflog(); err != nil {
+					return err
+				}
+			}
+		case "undo":
+			{
+				if len(command_list) != 1 {
+					return er.CLIUndoErr
+				}
+				if err := tr.Undo(); err != nil {
 					return err
 				}
 			}
@@ -1,14 +1,18 @@
 package cli
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
+	cp "github.com/mainak55512/qwe/compressor"
 	in "github.com/mainak55512/qwe/initializer"
 	er "github.com/mainak55512/qwe/qwerror"
 	utl "github.com/mainak55512/qwe/qweutils"
+	st "github.com/mainak55512/qwe/stash"
 	tr "github.com/mainak55512/qwe/tracker"
 )
 
@@ -49,3 +53,216 @@ func TestHandleArgsUntrack(t *testing.T) {
 		t.Errorf("expected CLIUntrackErr, got %v", err)
 	}
 }
+
+func TestHandleArgsUndo(t *testing.T) {
+	t.Chdir(t.TempDir())
+	originalArgs := os.Args
+	t.Cleanup(func() { os.Args = originalArgs })
+
+	run := func(args ...string) {
+		t.Helper()
+		os.Args = append([]string{"qwe"}, args...)
+		if err := HandleArgs(); err != nil {
+			t.Fatalf("%v command failed: %v", args, err)
+		}
+	}
+
+	filePath := "notes.txt"
+	run("init")
+	if err := os.WriteFile(filePath, []byte("first\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	run("track", filePath)
+	if err := os.WriteFile(filePath, []byte("second\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	run("commit", filePath, "second")
+	run("untrack", filePath)
+
+	// Undo untrack, the file is tracked again with its history
+	run("undo")
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	val, ok := tracker[utl.Hasher(filePath)]
+	if !ok {
+		t.Fatal("undo did not restore untracked file")
+	}
+	if len(val.Versions) != 1 || val.Current != val.Versions[0].UID {
+		t.Errorf("undo did not restore file history: %+v", val)
+	}
+
+	// Undo commit, the file points back to its base version
+	run("undo")
+	tracker, _, err = tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	val = tracker[utl.Hasher(filePath)]
+	if val.Current != val.Base || len(val.Versions) != 0 {
+		t.Errorf("undo did not restore state before commit: %+v", val)
+	}
+
+	run("undo")
+	tracker, _, err = tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	if _, ok := tracker[utl.Hasher(filePath)]; ok {
+		t.Error("undo did not remove tracking of the file")
+	}
+
+	os.Args = []string{"qwe", "undo"}
+	if err := HandleArgs(); !errors.Is(err, er.NothingToUndo) {
+		t.Errorf("expected NothingToUndo, got %v", err)
+	}
+	run("reflog")
+}
+
+func TestHandleArgsRejectsUnknownFlags(t *testing.T) {
+	t.Chdir(t.TempDir())
+	originalArgs := os.Args
+	t.Cleanup(func() { os.Args = originalArgs })
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+
+	filePath := "notes.txt"
+	if err := os.WriteFile(filePath, []byte("first\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := tr.StartTracking(filePath); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte("changed\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+
+	cases := []struct {
+		args []string
+		want error
+	}{
+		{[]string{"revert", filePath, "--froce"}, er.CLIRevertErr},
+		{[]string{"group-revert", "docs", "0", "--froce"}, er.CLIGrpRevertErr},
+		{[]string{"rebase", filePath, "--stahs"}, er.CLIRebaseErr},
+		{[]string{"stash", filePath, "--force"}, er.CLIStashErr},
+		{[]string{"log", "--onelin"}, er.CLILogErr},
+	}
+	for _, c := range cases {
+		os.Args = append([]string{"qwe"}, c.args...)
+		if err := HandleArgs(); !errors.Is(err, c.want) {
+			t.Errorf("%v: expected %v, got %v", c.args, c.want, err)
+		}
+	}
+	if content, _ := os.ReadFile(filePath); string(content) != "changed\n" {
+		t.Errorf("a rejected command changed the working file: %q", content)
+	}
+}
+
+func TestHandleArgsDashArguments(t *testing.T) {
+	t.Chdir(t.TempDir())
+	originalArgs := os.Args
+	t.Cleanup(func() { os.Args = originalArgs })
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+
+	filePath := "notes.txt"
+	if err := os.WriteFile(filePath, []byte("first\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := tr.StartTracking(filePath); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+
+	// Stash messages that look like flags are kept as they are
+	for _, args := range [][]string{{"stash", filePath, "-wip"}, {"stash", filePath, "--", "--wip"}} {
+		if err := os.WriteFile(filePath, []byte("changed\n"), 0o644); err != nil {
+			t.Fatalf("failed to modify file: %v", err)
+		}
+		os.Args = append([]string{"qwe"}, args...)
+		if err := HandleArgs(); err != nil {
+			t.Fatalf("%v: stash failed: %v", args, err)
+		}
+	}
+	content, err := cp.ReadFile(".qwe/_stash.qwe")
+	if err != nil {
+		t.Fatalf("failed to read stashes: %v", err)
+	}
+	var stashes st.StashSchema
+	if err := json.Unmarshal(content, &stashes); err != nil {
+		t.Fatalf("failed to parse stashes: %v", err)
+	}
+	if len(stashes) != 2 || stashes[0].Message != "-wip" || stashes[1].Message != "--wip" {
+		t.Errorf("expected the stash messages -wip and --wip, got %+v", stashes)
+	}
+}
+
+func TestHandleArgsGroupCommitMessage(t *testing.T) {
+	t.Chdir(t.TempDir())
+	originalArgs := os.Args
+	t.Cleanup(func() { os.Args = originalArgs })
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte("first\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	if err := tr.StartGroupTracking("docs", []string{"notes.txt"}); err != nil {
+		t.Fatalf("failed to track file in group: %v", err)
+	}
+
+	messages := []string{"-fix: typo", "--allow-empty"}
+	for i, args := range [][]string{{"group-commit", "docs", messages[0]}, {"group-commit", "docs", "--", messages[1]}} {
+		if err := os.WriteFile("notes.txt", []byte(fmt.Sprintf("change %d\n", i)), 0o644); err != nil {
+			t.Fatalf("failed to modify file: %v", err)
+		}
+		os.Args = append([]string{"qwe"}, args...)
+		if err := HandleArgs(); err != nil {
+			t.Fatalf("%v: group-commit failed: %v", args, err)
+		}
+	}
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read group tracker: %v", err)
+	}
+	gr := groupTracker[utl.Hasher("docs")]
+	if len(gr.VersionOrder) != 3 {
+		t.Fatalf("expected two group commits, got %+v", gr.VersionOrder)
+	}
+	for i, message := range messages {
+		if got := gr.Versions[gr.VersionOrder[i+1]].CommitMessage; got != message {
+			t.Errorf("expected commit message %q, got %q", message, got)
+		}
+	}
+}
+
+func TestHandleArgsGrepDashPattern(t *testing.T) {
+	t.Chdir(t.TempDir())
+	originalArgs := os.Args
+	t.Cleanup(func() { os.Args = originalArgs })
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile("Makefile", []byte("CFLAGS = -Wall\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := tr.StartTracking("Makefile"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+
+	for _, args := range [][]string{{"grep", "-Wall"}, {"grep", "--", "-Wall"}, {"grep", "-Wall", "Makefile"}, {"grep", "-S", "-Wall"}} {
+		os.Args = append([]string{"qwe"}, args...)
+		if err := HandleArgs(); err != nil {
+			t.Errorf("%v: grep failed: %v", args, err)
+		}
+	}
+	os.Args = []string{"qwe", "grep", "--Wall"}
+	if err := HandleArgs(); !errors.Is(err, er.CLIGrepErr) {
+		t.Errorf("expected CLIGrepErr for an unknown flag, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Separates flags from positional arguments of a command.
+// Arguments starting with '--' are flags, an argument starting with a single '-' is only a flag if it names one of
+// the switches or valueFlags, so messages and patterns like '-fix' stay positional. Every argument after '--' is positional.
+// Flags listed in valueFlags take a value either as '--flag value' or '--flag=value',
+// every other flag is treated as a switch and stored as "true".
+func parseFlags(args []string, switches []string, valueFlags ...string) ([]string, map[string]string, error) {
+	var positional []string
+	flags := make(map[string]string)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		value := "true"
+		hasValue := false
+		if idx := strings.Index(name, "="); idx != -1 {
+			name, value = name[:idx], name[idx+1:]
+			hasValue = true
+		}
+		if !strings.HasPrefix(arg, "--") && !slices.Contains(switches, name) && !slices.Contains(valueFlags, name) {
+			positional = append(positional, arg)
+			continue
+		}
+		for _, vf := range valueFlags {
+			if vf != name || hasValue {
+				continue
+			}
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("Flag --%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		flags[name] = value
+	}
+	return positional, flags, nil
+}
+
+// Reports whether every parsed flag is one of the allowed names, so a misspelled flag is never ignored
+func knownFlags(flags map[string]string, allowed ...string) bool {
+	for name := range flags {
+		if !slices.Contains(allowed, name) {
+			return false
+		}
+	}
+	return true
+}
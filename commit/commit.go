@@ -1,8 +1,6 @@
 package commit
 
 import (
-	"bufio"
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,7 +11,6 @@ import (
 	tw "text/tabwriter"
 
 	bh "github.com/mainak55512/qwe/binaryhandler"
-	cp "github.com/mainak55512/qwe/compressor"
 	er "github.com/mainak55512/qwe/qwerror"
 	utl "github.com/mainak55512/qwe/qweutils"
 	res "github.com/mainak55512/qwe/reconstruct"
@@ -32,9 +29,7 @@ func CommitUnit(filePath, message string) (string, int, error) {
 	// Create hash of file name, it will be used later to retrive file details from tracker
 	fileId := utl.Hasher(filePath)
 
-	// hash from file name and current time, will be used later as the file name of the commit
-	fileObjectId := utl.Hasher(fmt.Sprintf("%s%d", filePath, time.Now().UnixNano()))
-
+	var fileObjectId string
 	var commitID int
 
 	// Check if file is tracked
@@ -45,86 +40,57 @@ func CommitUnit(filePath, message string) (string, int, error) {
 				if errors.Is(err, er.NoFileOrDiff) {
 					for i := range val.Versions {
 						if val.Versions[i].UID == val.Current {
-							return "", i, err
+							return val.Current, i, err
 						}
 					}
-					return "", -2, err // this means asset is in base version
+					return val.Base, -2, err // this means asset is in base version
 				}
 				return "", -3, err
 			}
 		} else {
-			target := ".qwe/_object/" + fileObjectId
+			latestID := res.BaseVersion
+			if len(val.Versions) > 0 {
+				latestID = len(val.Versions) - 1
+			}
+			noChange := func() (string, int, error) {
+				if latestID == res.BaseVersion {
+					return val.Base, -2, er.NoFileOrDiff
+				}
+				// todo improve user experience and say what accurately happened
+				return val.Versions[latestID].UID, latestID, er.NoFileOrDiff
+			}
 
 			// This is the latest version of uncommitted file changes
-			new_file, err := os.Open(filePath)
+			working, err := os.ReadFile(filePath)
 			if err != nil {
-				// return "", -3, err // -3 means unsuccessful
-				return val.Versions[len(val.Versions)-1].UID, len(val.Versions) - 1, er.NoFileOrDiff
+				return noChange()
 			}
-			defer new_file.Close()
 
 			// Reconstruct the file to the latest committed version
 			// by applying all the changes to the base version
-			if err = res.Reconstruct(val, target, res.LastVersion); err != nil {
+			latest, err := res.Content(val, latestID)
+			if err != nil {
 				return "", -3, err // -3 means unsuccessful
 			}
 
-			current_file, err := os.Open(target)
+			// This ensures no redundent commits are created for the file if there is no change
+			stat, err := LineStat(latest, working)
 			if err != nil {
 				return "", -3, err
 			}
-
-			current_scanner := bufio.NewScanner(current_file)
-
-			new_scanner := bufio.NewScanner(new_file)
-
-			var diff_content string
-
-			// Find the difference between latest uncommitted and committed versions and store that in diff_content
-			// difference is stored as <line-number> @@@ <new string value>
-			line := 0
-			for new_scanner.Scan() {
-				line++
-				current_scanner.Scan()
-				if !bytes.Equal(current_scanner.Bytes(), new_scanner.Bytes()) {
-					diff_content += fmt.Sprintf("%d @@@ %s\n", line, utl.ConvStrEnc(new_scanner.Text()))
-				}
+			if stat == [3]int{} {
+				return noChange()
 			}
 
-			// This ensures no redundent commits are created for the file if there is no change
-			if diff_content == "" {
-				if !current_scanner.Scan() {
-					os.Remove(target)
-					if strings.HasPrefix(val.Current, "_base_") && len(val.Versions) == 0 {
-						return val.Base, -2, er.NoFileOrDiff
-					}
-					// todo improve user experience and say what accurately happened
-					return val.Versions[len(val.Versions)-1].UID, len(val.Versions) - 1, er.NoFileOrDiff
-				}
-			}
-
-			// Adding total line number of uncommitted file on top of the diff_content
-			// This line number will be used while reconstructing the file later
-			diff_content = fmt.Sprintf("%d\n%s", line, diff_content)
-			current_file.Close()
-
-			output_content, err := os.Create(target)
-			if err != nil {
-				return "", -3, err // -3 means unsuccessful
-			}
-
-			output_writer := bufio.NewWriter(output_content)
-			_, err = output_writer.WriteString(diff_content)
+			// Find the difference between latest uncommitted and committed versions,
+			// difference is stored as <line-number> @@@ <new string value> below the line count of the file
+			delta, err := TextDelta(latest, working)
 			if err != nil {
-				return "", -3, er.BaseWriteErr // -3 means unsuccessful
-			}
-			if err = output_writer.Flush(); err != nil {
-				return "", -3, er.OutputWriteErr // -3 means unsuccessful
+				return "", -3, err
 			}
-			output_content.Close()
 
-			// Compressing the commit file
-			if err = cp.CompressFile(target); err != nil {
+			// Writing the compressed commit file
+			if fileObjectId, err = WriteObject("", filePath, []byte(delta)); err != nil {
 				return "", -3, err // -3 means unsuccessful
 			}
 		}
@@ -134,6 +100,7 @@ func CommitUnit(filePath, message string) (string, int, error) {
 			UID:           fileObjectId,
 			CommitMessage: message,
 			TimeStamp:     time.Now().String()[:16],
+			Author:        utl.Author(),
 		})
 		val.Current = fileObjectId
 		tracker[fileId] = val
@@ -158,132 +125,93 @@ func CommitUnit(filePath, message string) (string, int, error) {
 	return fileObjectId, commitID, nil
 }
 
-// Commit all file changes that are tracked in the group
-func CommitGroup(groupName, commitMessage string) error {
+// Returns the commits of a tracked file, the position of a commit is its commit id
+func CommitList(filePath string) ([]tr.VersionDetails, error) {
 
-	// Get group tracker
-	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	// Get tracker details
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
 	if err != nil {
-		return err
-	}
-
-	groupID := utl.Hasher(groupName)
-	groupObjID := utl.Hasher(fmt.Sprintf("%s%d", groupName, time.Now().UnixNano()))
-
-	// Check if valid group
-	gr, ok := groupTracker[groupID]
-	if !ok {
-		return er.InvalidGroup
+		return nil, err
 	}
 
-	// version order array maintains the order of commit history, appending new commit version here
-	gr.VersionOrder = append(gr.VersionOrder, groupObjID)
-
-	// Fetching the current group commit
-	current, ok := gr.Versions[gr.Current]
+	val, ok := tracker[utl.Hasher(filePath)]
 	if !ok {
-		return er.CurrentGrpErr
+		return nil, er.FileNotTracked
 	}
-
-	// newFiles contains the modified file details for the new commit
-	newFiles := make(map[string]tr.FileDetails)
-
-	for k := range current.Files {
-
-		// Commit each and every file that is tracked in the group
-		fileObjectID, commitID, err := CommitUnit(current.Files[k].FileName, commitMessage)
-
-		// Do not treat it as error if there is no change in the file
-		if err != nil && !errors.Is(err, er.NoFileOrDiff) {
-			return err
-		}
-
-		// Add modified file details to newFiles
-		newFiles[k] = tr.FileDetails{
-			FileName:     current.Files[k].FileName,
-			CommitNumber: commitID,
-			FileObjID:    fileObjectID,
-		}
-	}
-
-	// Update current version with the newly created commit in the group tracker
-	gr.Current = groupObjID
-
-	// Add new entry to the versions details of the group tracker
-	gr.Versions[groupObjID] = tr.GroupVersionDetails{
-		CommitMessage: commitMessage,
-		Files:         newFiles,
-	}
-
-	commitID := len(gr.Versions) - 1
-
-	// Update the group tracker with new details
-	groupTracker[groupID] = gr
-
-	marshalContent, err := json.MarshalIndent(groupTracker, "", " ")
-	if err != nil {
-		return er.CommitUnsuccessful
-	}
-
-	// Update the tracker
-	if err = tr.SaveTracker(1, marshalContent); err != nil {
-		return err
-	}
-	fmt.Println("Successfully committed to group", groupName, "with commit id", commitID)
-	return nil
+	return val.Versions, nil
 }
 
 // Prints the commit history with CommitID, Commit message, and time stamp details
 func GetCommitList(filePath string) error {
 
-	// Get tracker details
-	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	versions, err := CommitList(filePath)
 	if err != nil {
 		return err
 	}
 
-	fileId := utl.Hasher(filePath)
-
 	w := new(tw.Writer)
 	w.Init(os.Stdout, 0, 0, 0, ' ', tw.TabIndent)
 
-	// Loop through versions of the file and print commitID, commit message and time stamp for each entry
-	for i, e := range tracker[fileId].Versions {
-		fmt.Fprintln(w,
-			fmt.Sprintf(
-				"\nID:\t%d\nCommit Message:\t%s\nTime Stamp:\t%s\n", i, e.CommitMessage, e.TimeStamp,
-			),
-		)
+	// Loop through versions of the file and print commitID, commit message, author and time stamp for each entry
+	for i, e := range versions {
+		if e.Author != "" {
+			fmt.Fprintln(w,
+				fmt.Sprintf(
+					"\nID:\t%d\nCommit Message:\t%s\nAuthor:\t%s\nTime Stamp:\t%s\n", i, e.CommitMessage, e.Author, e.TimeStamp,
+				),
+			)
+		} else {
+			fmt.Fprintln(w,
+				fmt.Sprintf(
+					"\nID:\t%d\nCommit Message:\t%s\nTime Stamp:\t%s\n", i, e.CommitMessage, e.TimeStamp,
+				),
+			)
+		}
 		w.Flush()
 	}
 	return nil
 }
 
-// Shows list of all commits of the specified group
-func GetGroupCommitList(groupName string) error {
+// Returns the versions of a group in commit order, the position of a version is its commit id
+func GroupCommitList(groupName string) ([]tr.GroupVersionDetails, error) {
 
 	// Get group tracker
 	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	groupID := utl.Hasher(groupName)
-
 	// Check if valid group
-	gr, ok := groupTracker[groupID]
+	gr, ok := groupTracker[utl.Hasher(groupName)]
 	if !ok {
-		return er.InvalidGroup
+		return nil, er.InvalidGroup
+	}
+
+	versions := make([]tr.GroupVersionDetails, len(gr.VersionOrder))
+	for i, k := range gr.VersionOrder {
+		versions[i] = gr.Versions[k]
+	}
+	return versions, nil
+}
+
+// Shows list of all commits of the specified group
+func GetGroupCommitList(groupName string) error {
+
+	versions, err := GroupCommitList(groupName)
+	if err != nil {
+		return err
 	}
 
 	w := new(tw.Writer)
 	w.Init(os.Stdout, 0, 0, 0, ' ', tw.TabIndent)
 
 	// Print every version details
-	i := 0
-	for _, k := range gr.VersionOrder {
-		fmt.Fprintln(w, fmt.Sprintf("\nID:\t%d\nCommit Message:\t%s\n", i, gr.Versions[k].CommitMessage))
-		i++
+	for i, v := range versions {
+		if v.TimeStamp != "" {
+			fmt.Fprintln(w, fmt.Sprintf("\nID:\t%d\nCommit Message:\t%s\nTime Stamp:\t%s\n", i, v.CommitMessage, v.TimeStamp))
+		} else {
+			fmt.Fprintln(w, fmt.Sprintf("\nID:\t%d\nCommit Message:\t%s\n", i, v.CommitMessage))
+		}
 	}
 	w.Flush()
 	return nil
@@ -329,13 +257,41 @@ func CurrentCommit(filePath string) error {
 	return nil
 }
 
-// Prints current group commit details
-func GroupCommitDetails(groupName string, commitNumber int) error {
+// Details of a group version. Commits holds the commit ids of its files by file id, -2 for the base version,
+// files whose commit failed or is no longer part of their history are left out.
+type GroupVersionInfo struct {
+	Name     string                 `json:"name"`
+	CommitID int                    `json:"commit_id"`
+	Version  tr.GroupVersionDetails `json:"version"`
+	Commits  map[string]int         `json:"commits"`
+}
+
+// Resolves the commit UIDs a group version refers to into commit ids of the files, see GroupVersionInfo
+func FileCommits(version tr.GroupVersionDetails) (map[string]int, error) {
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return nil, err
+	}
+	commits := make(map[string]int)
+	for fileID, f := range version.Files {
+		val, ok := tracker[fileID]
+		if !ok {
+			continue
+		}
+		if commitID, ok := val.CommitID(f.UID); ok {
+			commits[fileID] = commitID
+		}
+	}
+	return commits, nil
+}
+
+// Returns the details of a group version, commitNumber -1 returns the current version
+func GroupVersion(groupName string, commitNumber int) (*GroupVersionInfo, error) {
 
 	// Get group tracker
 	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	groupID := utl.Hasher(groupName)
@@ -343,7 +299,7 @@ func GroupCommitDetails(groupName string, commitNumber int) error {
 	// Check if valid group
 	val, ok := groupTracker[groupID]
 	if !ok {
-		return er.InvalidGroup
+		return nil, er.InvalidGroup
 	}
 
 	// Get the commit id of specific version from the group tracker
@@ -355,8 +311,8 @@ func GroupCommitDetails(groupName string, commitNumber int) error {
 	if commitNumber == -1 {
 		commit = val.Current
 	} else {
-		if commitNumber > len(val.VersionOrder)-1 {
-			return er.InvalidCommitNo
+		if commitNumber < -1 || commitNumber > len(val.VersionOrder)-1 {
+			return nil, er.InvalidCommitNo
 		}
 		commit = val.VersionOrder[commitNumber]
 	}
@@ -368,14 +324,39 @@ func GroupCommitDetails(groupName string, commitNumber int) error {
 		}
 	}
 
+	commits, err := FileCommits(val.Versions[commit])
+	if err != nil {
+		return nil, err
+	}
+
+	return &GroupVersionInfo{
+		Name:     val.GroupName,
+		CommitID: commitID,
+		Version:  val.Versions[commit],
+		Commits:  commits,
+	}, nil
+}
+
+// Prints current group commit details
+func GroupCommitDetails(groupName string, commitNumber int) error {
+
+	info, err := GroupVersion(groupName, commitNumber)
+	if err != nil {
+		return err
+	}
+
 	// Print current commit details
 	w := new(tw.Writer)
 	w.Init(os.Stdout, 0, 0, 0, ' ', tw.TabIndent)
-	fmt.Fprintf(w, "\nName:\t %s\nCurrent Commit ID:\t %d\nCommit Message:\t %s\n", val.GroupName, commitID, val.Versions[commit].CommitMessage)
-	files := val.Versions[commit].Files
+	fmt.Fprintf(w, "\nName:\t %s\nCurrent Commit ID:\t %d\nCommit Message:\t %s\n", info.Name, info.CommitID, info.Version.CommitMessage)
+	files := info.Version.Files
 	fmt.Fprintf(w, "\nAssociated files:\n")
 	for e := range files {
-		fmt.Fprintf(w, "File: %s, \tCommitID: %d\n", files[e].FileName, files[e].CommitNumber)
+		if commitID, ok := info.Commits[e]; ok {
+			fmt.Fprintf(w, "File: %s, \tCommitID: %d\n", files[e].FileName, commitID)
+		} else {
+			fmt.Fprintf(w, "File: %s, \tCommitID: unavailable\n", files[e].FileName)
+		}
 	}
 	w.Flush()
 	return nil
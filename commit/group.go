@@ -0,0 +1,337 @@
+package commit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	tw "text/tabwriter"
+	"time"
+
+	bh "github.com/mainak55512/qwe/binaryhandler"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	res "github.com/mainak55512/qwe/reconstruct"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// Uncommitted change of a file of a group. Lines count the changed, added and removed lines of text files.
+// UID is the commit the next group version refers to if the file has no change.
+type GroupFileChange struct {
+	FileID   string
+	FileName string
+	Changed  bool
+	Binary   bool
+	Lines    [3]int
+	UID      string
+
+	recorded []byte // content of the commit a changed text file is compared with
+	working  []byte
+}
+
+// Counts the lines that differ between two text contents the same way CommitUnit records them:
+// lines replaced at the same position, lines added at the end and lines removed from the end
+func LineStat(prev, next []byte) ([3]int, error) {
+	var stat [3]int
+	_, err := utl.CompareLines(bytes.NewReader(prev), bytes.NewReader(next), func(change utl.LineChange, _ int, _, _ string) {
+		stat[change]++
+	})
+	return stat, err
+}
+
+// Determines the uncommitted change of a file, text files are compared with their latest commit
+// and binary files with their checked out commit, as CommitUnit does
+func pendingChange(val tr.Tracker, fileID, filePath string) (GroupFileChange, error) {
+	change := GroupFileChange{
+		FileID:   fileID,
+		FileName: filePath,
+		Binary:   strings.HasPrefix(val.Base, "_bin_"),
+	}
+	commitID := res.BaseVersion
+	if change.Binary {
+		commitID, _ = val.CommitID(val.Current)
+	} else if len(val.Versions) > 0 {
+		commitID = len(val.Versions) - 1
+	}
+	change.UID = val.Base
+	if commitID != res.BaseVersion {
+		change.UID = val.Versions[commitID].UID
+	}
+
+	// A missing file keeps its latest commit
+	if !utl.FileExists(filePath) {
+		return change, nil
+	}
+	// Binary files are compared while they are read, their objects are written from the working file
+	if change.Binary {
+		same, err := bh.MatchesFile(change.UID, filePath)
+		change.Changed = !same
+		return change, err
+	}
+	recorded, err := res.Content(val, commitID)
+	if err != nil {
+		return change, err
+	}
+	working, err := os.ReadFile(filePath)
+	if err != nil {
+		return change, err
+	}
+	if change.Lines, err = LineStat(recorded, working); err != nil {
+		return change, err
+	}
+	change.Changed = change.Lines != [3]int{}
+	if change.Changed {
+		change.recorded, change.working = recorded, working
+	}
+	return change, nil
+}
+
+// Determines the uncommitted changes of the files of the current group version, sorted by file name.
+// Files left out with group-untrack are not included.
+func GroupChanges(gr tr.GroupTracker) ([]GroupFileChange, error) {
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return nil, err
+	}
+	return groupChanges(gr, tracker)
+}
+
+func groupChanges(gr tr.GroupTracker, tracker tr.TrackerSchema) ([]GroupFileChange, error) {
+	current, ok := gr.Versions[gr.Current]
+	if !ok {
+		return nil, er.CurrentGrpErr
+	}
+	var changes []GroupFileChange
+	for fileID, f := range current.Files {
+		if slices.Contains(gr.Untracked, fileID) {
+			continue
+		}
+		if _, ok := tracker[fileID]; !ok {
+			continue
+		}
+		changes = append(changes, GroupFileChange{FileID: fileID, FileName: f.FileName})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].FileName < changes[j].FileName
+	})
+
+	// Files have their own objects, so their changes are determined concurrently
+	err := utl.Parallel(len(changes), func(i int) error {
+		change, err := pendingChange(tracker[changes[i].FileID], changes[i].FileID, changes[i].FileName)
+		if err != nil {
+			return err
+		}
+		changes[i] = change
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Writes the commit object of a changed file, nothing refers to it until the tracker is saved
+func stageCommit(change GroupFileChange) (string, error) {
+	if change.Binary {
+		return bh.CommitBinFile(change.FileName, change.UID)
+	}
+	delta, err := TextDelta(change.recorded, change.working)
+	if err != nil {
+		return "", err
+	}
+	return WriteObject("", change.FileName, []byte(delta))
+}
+
+// Commit the changed files of the group and record a new group version.
+// Nothing is committed if no file changed and the group version would stay the same, unless allowEmpty is set.
+// With dryRun the files that would be committed are only listed, an empty preview is not an error.
+// The commit is all-or-nothing: objects of every file are written first, then the file tracker and the group tracker
+// are saved and written, if any step fails the written objects are removed and both trackers are restored.
+// Trackers are read and written once, changes and objects of the files are computed concurrently.
+func CommitGroup(groupName, commitMessage string, allowEmpty, dryRun bool) (err error) {
+
+	// Get trackers
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return err
+	}
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		return err
+	}
+
+	groupID := utl.Hasher(groupName)
+	groupObjID := utl.Hasher(fmt.Sprintf("%s%d", groupName, time.Now().UnixNano()))
+
+	// Check if valid group
+	gr, ok := groupTracker[groupID]
+	if !ok {
+		return er.InvalidGroup
+	}
+
+	// Fetching the current group commit
+	current, ok := gr.Versions[gr.Current]
+	if !ok {
+		return er.CurrentGrpErr
+	}
+
+	changes, err := groupChanges(gr, tracker)
+	if err != nil {
+		return err
+	}
+
+	// newFiles contains the file details for the new commit, unchanged files refer to their latest commit
+	newFiles := make(map[string]tr.FileDetails)
+	committed := 0
+	for _, change := range changes {
+		newFiles[change.FileID] = tr.FileDetails{FileName: change.FileName, UID: change.UID}
+		if change.Changed {
+			committed++
+		}
+	}
+	if dryRun {
+		printGroupChanges(groupName, changes, current)
+		return nil
+	}
+	if !allowEmpty && committed == 0 && maps.Equal(newFiles, current.Files) {
+		return er.NoGroupChanges
+	}
+
+	previous, err := json.MarshalIndent(tracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+	previousGroup, err := json.MarshalIndent(groupTracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+
+	// Roll back everything written so far if any step fails
+	var staged []string
+	trackerSaved := false
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, objID := range staged {
+			os.Remove(".qwe/_object/" + objID)
+		}
+		if trackerSaved {
+			if restoreErr := tr.RestoreTrackers(previous, previousGroup); restoreErr != nil {
+				fmt.Printf("Warning: failed to restore the trackers: %v\n", restoreErr)
+			}
+		}
+	}()
+
+	// Write the objects of the changed files concurrently, the trackers are only updated once all of them are written
+	objIDs := make([]string, len(changes))
+	err = utl.Parallel(len(changes), func(i int) error {
+		if !changes[i].Changed {
+			return nil
+		}
+		objID, err := stageCommit(changes[i])
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, changes[i].FileName)
+		}
+		objIDs[i] = objID
+		return nil
+	})
+	for _, objID := range objIDs {
+		if objID != "" {
+			staged = append(staged, objID)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	timeStamp := time.Now().String()[:16]
+	for i, change := range changes {
+		if !change.Changed {
+			continue
+		}
+		val := tracker[change.FileID]
+		val.Versions = append(val.Versions, tr.VersionDetails{
+			UID:           objIDs[i],
+			CommitMessage: commitMessage,
+			TimeStamp:     timeStamp,
+			Author:        utl.Author(),
+		})
+		val.Current = objIDs[i]
+		tracker[change.FileID] = val
+		newFiles[change.FileID] = tr.FileDetails{FileName: change.FileName, UID: objIDs[i]}
+	}
+
+	// version order array maintains the order of commit history, appending new commit version here
+	gr.VersionOrder = append(gr.VersionOrder, groupObjID)
+
+	// Update current version with the newly created commit in the group tracker
+	gr.Current = groupObjID
+	gr.Untracked = nil
+
+	// Add new entry to the versions details of the group tracker
+	gr.Versions[groupObjID] = tr.GroupVersionDetails{
+		CommitMessage: commitMessage,
+		TimeStamp:     timeStamp,
+		Author:        utl.Author(),
+		Files:         newFiles,
+	}
+
+	commitID := len(gr.Versions) - 1
+
+	// Update the group tracker with new details
+	groupTracker[groupID] = gr
+
+	trackerContent, err := json.MarshalIndent(tracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+	groupTrackerContent, err := json.MarshalIndent(groupTracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+
+	// Publish the file commits first, the group version refers to them. The trackers are on disk before success
+	// is reported, also when the command defers its writes.
+	trackerSaved = true
+	if committed > 0 {
+		if err = tr.SaveTracker(tr.FileTrackerType, trackerContent); err != nil {
+			return err
+		}
+	}
+	if err = tr.SaveTracker(tr.GroupTrackerType, groupTrackerContent); err != nil {
+		return err
+	}
+	if err = tr.SyncSession(); err != nil {
+		return err
+	}
+	fmt.Printf("Successfully committed to group %s with commit id %d: %d files committed, %d unchanged\n",
+		groupName, commitID, committed, len(changes)-committed)
+	return nil
+}
+
+// Prints the files a group commit would record
+func printGroupChanges(groupName string, changes []GroupFileChange, current tr.GroupVersionDetails) {
+	fmt.Printf("Would commit to group %s:\n\n", groupName)
+	w := new(tw.Writer)
+	w.Init(os.Stdout, 0, 0, 2, ' ', 0)
+	committed := 0
+	for _, change := range changes {
+		switch {
+		case change.Changed && change.Binary:
+			committed++
+			fmt.Fprintf(w, "%s\tbinary content changed\n", change.FileName)
+		case change.Changed:
+			committed++
+			fmt.Fprintf(w, "%s\t%d lines changed, %d added, %d removed\n", change.FileName, change.Lines[0], change.Lines[1], change.Lines[2])
+		case current.Files[change.FileID].UID != change.UID:
+			fmt.Fprintf(w, "%s\tunchanged, refers to its latest commit\n", change.FileName)
+		}
+	}
+	w.Flush()
+	fmt.Printf("\n%d files would be committed, %d unchanged\n", committed, len(changes)-committed)
+}
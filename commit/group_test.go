@@ -0,0 +1,285 @@
+package commit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	cp "github.com/mainak55512/qwe/compressor"
+	in "github.com/mainak55512/qwe/initializer"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+func TestGroupManagement(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	for _, filePath := range []string{"a.txt", "b.txt", "shared.txt"} {
+		if err := os.WriteFile(filePath, []byte("base\n"), 0o644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+	for _, groupName := range []string{"docs", "other"} {
+		if err := in.GroupInit(groupName); err != nil {
+			t.Fatalf("failed to initialize group: %v", err)
+		}
+	}
+	if err := tr.StartGroupTracking("docs", []string{"a.txt", "b.txt", "shared.txt"}); err != nil {
+		t.Fatalf("failed to track files in group: %v", err)
+	}
+	if err := tr.StartGroupTracking("other", []string{"shared.txt"}); err != nil {
+		t.Fatalf("failed to track file in group: %v", err)
+	}
+
+	// group-untrack only affects the next commit, group-track undoes it
+	if err := tr.StopGroupTracking("docs", "missing.txt"); !errors.Is(err, er.FileNotInGroup) {
+		t.Errorf("expected FileNotInGroup, got %v", err)
+	}
+	for _, filePath := range []string{"a.txt", "b.txt"} {
+		if err := tr.StopGroupTracking("docs", filePath); err != nil {
+			t.Fatalf("StopGroupTracking() failed: %v", err)
+		}
+	}
+	if err := tr.StartGroupTracking("docs", []string{"a.txt"}); err != nil {
+		t.Fatalf("failed to track file in group again: %v", err)
+	}
+	if err := CommitGroup("docs", "Without b", false, false); err != nil {
+		t.Fatalf("failed to commit group: %v", err)
+	}
+	files, err := GroupVersion("docs", -1)
+	if err != nil {
+		t.Fatalf("GroupVersion() failed: %v", err)
+	}
+	if _, ok := files.Version.Files[utl.Hasher("b.txt")]; ok || len(files.Version.Files) != 2 {
+		t.Errorf("expected a.txt and shared.txt in the new group commit, got %+v", files.Version.Files)
+	}
+	first, err := GroupVersion("docs", 0)
+	if err != nil {
+		t.Fatalf("GroupVersion() failed: %v", err)
+	}
+	if len(first.Version.Files) != 3 {
+		t.Errorf("expected the first group commit to keep all files, got %+v", first.Version.Files)
+	}
+
+	// group-rename keeps the versions
+	if err := tr.RenameGroup("docs", "other"); !errors.Is(err, er.GrpAlreadyTracked) {
+		t.Errorf("expected GrpAlreadyTracked, got %v", err)
+	}
+	if err := tr.RenameGroup("docs", "manual"); err != nil {
+		t.Fatalf("RenameGroup() failed: %v", err)
+	}
+	versions, err := GroupCommitList("manual")
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("expected two versions of the renamed group, got %v, %v", versions, err)
+	}
+	if _, err := GroupCommitList("docs"); err == nil {
+		t.Error("expected the old group name to be gone")
+	}
+
+	// group-delete --purge untracks files that are not part of another group
+	if err := tr.DeleteGroup("manual", true); err != nil {
+		t.Fatalf("DeleteGroup() failed: %v", err)
+	}
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	trackedFiles, err := tr.LoadTrackedFilesFromFile(".qwe/" + tr.FileName)
+	if err != nil {
+		t.Fatalf("failed to read tracked files: %v", err)
+	}
+	for filePath, tracked := range map[string]bool{"a.txt": false, "b.txt": false, "shared.txt": true} {
+		if _, ok := tracker[utl.Hasher(filePath)]; ok != tracked {
+			t.Errorf("%s: expected tracked to be %v", filePath, tracked)
+		}
+		if _, ok := trackedFiles[utl.Hasher(filePath)]; ok != tracked {
+			t.Errorf("%s: expected listed in the tracked files to be %v", filePath, tracked)
+		}
+	}
+	if _, err := GroupCommitList("manual"); err == nil {
+		t.Error("expected the deleted group to be gone")
+	}
+}
+
+func TestCommitGroupSkipsEmptyCommits(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	for _, filePath := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filePath, []byte("one\ntwo\n"), 0o644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	if err := tr.StartGroupTracking("docs", []string{"a.txt", "b.txt"}); err != nil {
+		t.Fatalf("failed to track files in group: %v", err)
+	}
+	versions := func() int {
+		t.Helper()
+		list, err := GroupCommitList("docs")
+		if err != nil {
+			t.Fatalf("GroupCommitList() failed: %v", err)
+		}
+		return len(list)
+	}
+
+	if err := CommitGroup("docs", "Nothing", false, false); !errors.Is(err, er.NoGroupChanges) {
+		t.Errorf("expected NoGroupChanges, got %v", err)
+	}
+	if err := CommitGroup("docs", "Nothing", false, true); err != nil {
+		t.Errorf("expected an empty preview on a dry run without changes, got %v", err)
+	}
+	if err := CommitGroup("docs", "Checkpoint", true, false); err != nil {
+		t.Fatalf("CommitGroup() with allowEmpty failed: %v", err)
+	}
+	if versions() != 2 {
+		t.Fatalf("expected an empty group commit, got %d versions", versions())
+	}
+
+	if err := os.WriteFile("a.txt", []byte("one\nTWO\nthree\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	gr := groupOf(t, "docs")
+	changes, err := GroupChanges(gr)
+	if err != nil {
+		t.Fatalf("GroupChanges() failed: %v", err)
+	}
+	if len(changes) != 2 || !changes[0].Changed || changes[0].Lines != [3]int{1, 1, 0} || changes[1].Changed {
+		t.Errorf("expected a.txt with one changed and one added line, got %+v", changes)
+	}
+	if err := CommitGroup("docs", "Preview", false, true); err != nil {
+		t.Fatalf("CommitGroup() with dryRun failed: %v", err)
+	}
+	if versions() != 2 {
+		t.Errorf("expected no group commit on a dry run, got %d versions", versions())
+	}
+
+	if err := CommitGroup("docs", "Change a", false, false); err != nil {
+		t.Fatalf("CommitGroup() failed: %v", err)
+	}
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	if n := len(tracker[utl.Hasher("a.txt")].Versions); n != 1 {
+		t.Errorf("expected one commit of a.txt, got %d", n)
+	}
+	if n := len(tracker[utl.Hasher("b.txt")].Versions); n != 0 {
+		t.Errorf("expected no commit of the unchanged b.txt, got %d", n)
+	}
+}
+
+func groupOf(t *testing.T, groupName string) tr.GroupTracker {
+	t.Helper()
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read group tracker: %v", err)
+	}
+	return groupTracker[utl.Hasher(groupName)]
+}
+
+func TestCommitGroupRollback(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("QWE_WORKERS", "1")
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	for _, filePath := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filePath, []byte("one\n"), 0o644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	if err := tr.StartGroupTracking("docs", []string{"a.txt", "b.txt"}); err != nil {
+		t.Fatalf("failed to track files in group: %v", err)
+	}
+	if err := os.WriteFile("a.txt", []byte("two\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	if err := os.WriteFile("b.txt", []byte(strings.Repeat("a long line of b\n", 200)), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+
+	objects := func() []string {
+		t.Helper()
+		entries, err := os.ReadDir(".qwe/_object")
+		if err != nil {
+			t.Fatalf("failed to list objects: %v", err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+	before := objects()
+	trackerBefore, err := os.ReadFile(".qwe/_tracker.qwe")
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+
+	// The small commit of a.txt is staged first, the large commit of b.txt fails on an unknown codec
+	t.Cleanup(func() { cp.SetPolicy(cp.DefaultPolicy) })
+	cp.SetPolicy(cp.Policy{Codec: cp.Zlib, Large: cp.Codec(0x7f), LargeSize: 1024})
+	if err := CommitGroup("docs", "Change both", false, false); !errors.Is(err, er.OutputWriteErr) {
+		t.Fatalf("expected OutputWriteErr, got %v", err)
+	}
+
+	if after := objects(); !slices.Equal(before, after) {
+		t.Errorf("expected the staged objects to be removed, got %v instead of %v", after, before)
+	}
+	trackerAfter, err := os.ReadFile(".qwe/_tracker.qwe")
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	if !bytes.Equal(trackerBefore, trackerAfter) {
+		t.Error("expected the file tracker to be unchanged")
+	}
+	if gr := groupOf(t, "docs"); len(gr.VersionOrder) != 1 {
+		t.Errorf("expected no group commit, got %d versions", len(gr.VersionOrder))
+	}
+}
+
+func TestCommitGroupWritesDeferredTrackers(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile("a.txt", []byte("one\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	if err := tr.StartGroupTracking("docs", []string{"a.txt"}); err != nil {
+		t.Fatalf("failed to track file in group: %v", err)
+	}
+	if err := os.WriteFile("a.txt", []byte("two\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+
+	// The group commit is on disk once it reports success, even though the command defers its writes
+	tr.DeferWrites()
+	t.Cleanup(func() { tr.FlushSession() })
+	if err := CommitGroup("docs", "Change a", false, false); err != nil {
+		t.Fatalf("CommitGroup() failed: %v", err)
+	}
+	raw, err := cp.ReadFile(".qwe/_group_tracker.qwe")
+	if err != nil {
+		t.Fatalf("failed to read group tracker: %v", err)
+	}
+	if !strings.Contains(string(raw), "Change a") {
+		t.Error("expected the group commit to be written to disk")
+	}
+}
@@ -0,0 +1,230 @@
+package commit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	tw "text/tabwriter"
+
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// Layout of the time stamps stored in the trackers
+const TimeStampLayout = "2006-01-02 15:04"
+
+// Filters applied to the repository log, zero values disable a filter
+type LogFilter struct {
+	PathPrefix string
+	Group      string
+	Since      time.Time
+	Until      time.Time
+	Message    *regexp.Regexp
+	Author     string
+}
+
+// A single commit of a file or a group in the repository log
+type LogEntry struct {
+	IsGroup   bool
+	Name      string
+	CommitID  int
+	Message   string
+	Author    string
+	TimeStamp string
+	time      time.Time
+	files     []string
+}
+
+// Parses a date given on the command line, dates without time cover the whole day when used as upper bound
+func ParseLogDate(date string, endOfDay bool) (time.Time, error) {
+	if t, err := time.ParseInLocation(TimeStampLayout, date, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return time.Time{}, er.InvalidDate
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Minute)
+	}
+	return t, nil
+}
+
+// Compiles the message filter, patterns that are not valid regular expressions are matched literally
+func MessagePattern(pattern string) *regexp.Regexp {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		re = regexp.MustCompile(regexp.QuoteMeta(pattern))
+	}
+	return re
+}
+
+func (f LogFilter) match(e LogEntry, groupFiles map[string]struct{}) bool {
+	if f.PathPrefix != "" {
+		matched := false
+		for _, file := range e.files {
+			if strings.HasPrefix(filepath.ToSlash(file), filepath.ToSlash(f.PathPrefix)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.Group != "" {
+		if e.IsGroup && e.Name != f.Group {
+			return false
+		}
+		if !e.IsGroup {
+			if _, ok := groupFiles[e.Name]; !ok {
+				return false
+			}
+		}
+	}
+	if !f.Since.IsZero() && (e.time.IsZero() || e.time.Before(f.Since)) {
+		return false
+	}
+	if !f.Until.IsZero() && (e.time.IsZero() || e.time.After(f.Until)) {
+		return false
+	}
+	if f.Message != nil && !f.Message.MatchString(e.Message) {
+		return false
+	}
+	if f.Author != "" && !strings.Contains(strings.ToLower(e.Author), strings.ToLower(f.Author)) {
+		return false
+	}
+	return true
+}
+
+// Collects the commits of every tracked file and group matching the filter, latest first
+func Log(filter LogFilter) ([]LogEntry, error) {
+	if !utl.QweIsInWorkingDir() {
+		return nil, er.RepoNotFound
+	}
+
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return nil, err
+	}
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		return nil, err
+	}
+	trackedFiles, err := tr.LoadTrackedFilesFromFile(filepath.Join(tr.QweDir, tr.FileName))
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := func(fileID string) string {
+		if f, ok := trackedFiles[fileID]; ok {
+			return f.FilePath
+		}
+		return fileID
+	}
+
+	// Files of the current version of the filtered group
+	groupFiles := make(map[string]struct{})
+	if filter.Group != "" {
+		gr, ok := groupTracker[utl.Hasher(filter.Group)]
+		if !ok {
+			return nil, er.InvalidGroup
+		}
+		for _, f := range gr.Versions[gr.Current].Files {
+			groupFiles[f.FileName] = struct{}{}
+		}
+	}
+
+	var entries []LogEntry
+	for fileID, val := range tracker {
+		name := fileName(fileID)
+		for i, v := range val.Versions {
+			entries = append(entries, LogEntry{
+				Name:      name,
+				CommitID:  i,
+				Message:   v.CommitMessage,
+				Author:    v.Author,
+				TimeStamp: v.TimeStamp,
+				files:     []string{name},
+			})
+		}
+	}
+	for _, gr := range groupTracker {
+		for i, k := range gr.VersionOrder {
+			version := gr.Versions[k]
+			var files []string
+			for _, f := range version.Files {
+				files = append(files, f.FileName)
+			}
+			entries = append(entries, LogEntry{
+				IsGroup:   true,
+				Name:      gr.GroupName,
+				CommitID:  i,
+				Message:   version.CommitMessage,
+				Author:    version.Author,
+				TimeStamp: version.TimeStamp,
+				files:     files,
+			})
+		}
+	}
+
+	var result []LogEntry
+	for _, e := range entries {
+		e.time, _ = time.ParseInLocation(TimeStampLayout, e.TimeStamp, time.Local)
+		if filter.match(e, groupFiles) {
+			result = append(result, e)
+		}
+	}
+
+	// Latest commit first, commits without time stamp are the oldest
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].time.Equal(result[j].time) {
+			return result[i].time.After(result[j].time)
+		}
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].CommitID > result[j].CommitID
+	})
+	return result, nil
+}
+
+// Prints the commits of the whole repository matching the filter
+func PrintLog(filter LogFilter, oneLine bool) error {
+	entries, err := Log(filter)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No commits found!")
+		return nil
+	}
+
+	w := new(tw.Writer)
+	w.Init(os.Stdout, 0, 0, 1, ' ', tw.TabIndent)
+	for _, e := range entries {
+		kind := "File"
+		if e.IsGroup {
+			kind = "Group"
+		}
+		if oneLine {
+			fmt.Fprintf(w, "%s\t%s\t%s@%d\t%s\n", e.TimeStamp, strings.ToLower(kind), e.Name, e.CommitID, e.Message)
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\t%s\nID:\t%d\nCommit Message:\t%s\n", kind, e.Name, e.CommitID, e.Message)
+		if e.Author != "" {
+			fmt.Fprintf(w, "Author:\t%s\n", e.Author)
+		}
+		if e.TimeStamp != "" {
+			fmt.Fprintf(w, "Time Stamp:\t%s\n", e.TimeStamp)
+		}
+	}
+	w.Flush()
+	return nil
+}
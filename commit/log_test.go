@@ -0,0 +1,87 @@
+package commit
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	in "github.com/mainak55512/qwe/initializer"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+func TestLogFilters(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.Mkdir("conf", 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	for _, filePath := range []string{"notes.txt", "conf/app.ini"} {
+		if err := os.WriteFile(filePath, []byte("base\n"), 0o644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+		if _, err := tr.StartTracking(filePath); err != nil {
+			t.Fatalf("failed to track file: %v", err)
+		}
+	}
+
+	commit := func(author, filePath, content, message string) {
+		t.Helper()
+		t.Setenv("QWE_AUTHOR", author)
+		if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to modify file: %v", err)
+		}
+		if _, _, err := CommitUnit(filePath, message); err != nil {
+			t.Fatalf("failed to commit file: %v", err)
+		}
+	}
+	commit("alice", "notes.txt", "one\n", "Add meeting notes")
+	commit("bob", "conf/app.ini", "port=80\n", "Change port")
+	commit("alice", "conf/app.ini", "port=8080\n", "Fix port (again)")
+
+	t.Setenv("QWE_AUTHOR", "carol")
+	if err := in.GroupInit("config"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	if err := tr.StartGroupTracking("config", []string{"conf/app.ini"}); err != nil {
+		t.Fatalf("failed to track group: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   LogFilter
+		expected int
+	}{
+		{name: "no filter", filter: LogFilter{}, expected: 4},
+		{name: "path prefix", filter: LogFilter{PathPrefix: "conf/"}, expected: 3},
+		{name: "group", filter: LogFilter{Group: "config"}, expected: 3},
+		{name: "author", filter: LogFilter{Author: "alice"}, expected: 2},
+		{name: "message regex", filter: LogFilter{Message: MessagePattern("^(Change|Add) ")}, expected: 2},
+		{name: "literal message", filter: LogFilter{Message: MessagePattern("(again")}, expected: 1},
+		{name: "since tomorrow", filter: LogFilter{Since: time.Now().Add(24 * time.Hour)}, expected: 0},
+		{name: "until tomorrow", filter: LogFilter{Until: time.Now().Add(24 * time.Hour)}, expected: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Log(tt.filter)
+			if err != nil {
+				t.Fatalf("Log() failed: %v", err)
+			}
+			if len(entries) != tt.expected {
+				t.Errorf("expected %d entries, got %d: %+v", tt.expected, len(entries), entries)
+			}
+		})
+	}
+
+	if _, err := ParseLogDate("yesterday", false); err == nil {
+		t.Error("expected error for invalid date")
+	}
+	until, err := ParseLogDate("2025-01-02", true)
+	if err != nil {
+		t.Fatalf("ParseLogDate() failed: %v", err)
+	}
+	if until.Format(TimeStampLayout) != "2025-01-02 23:59" {
+		t.Errorf("expected end of day, got %s", until.Format(TimeStampLayout))
+	}
+}
@@ -0,0 +1,37 @@
+package commit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	cp "github.com/mainak55512/qwe/compressor"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+)
+
+// Builds the commit content that turns prev into next, in the same format CommitUnit records:
+// the line count of next followed by '<line-number> @@@ <encoded line>' for every changed line
+func TextDelta(prev, next []byte) (string, error) {
+	var delta strings.Builder
+	lines, err := utl.CompareLines(bytes.NewReader(prev), bytes.NewReader(next), func(change utl.LineChange, line int, _, nextLine string) {
+		if change != utl.LineRemoved {
+			fmt.Fprintf(&delta, "%d @@@ %s\n", line, utl.ConvStrEnc(nextLine))
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d\n%s", lines, delta.String()), nil
+}
+
+// Writes a compressed object and returns its id. prefix is '_base_' for base versions and empty for text commits,
+// binary commits are written by binaryhandler.WriteObject.
+func WriteObject(prefix, filePath string, content []byte) (string, error) {
+	objID := prefix + utl.Hasher(fmt.Sprintf("%s%d", filePath, time.Now().UnixNano()))
+	if err := cp.WriteFile(".qwe/_object/"+objID, content); err != nil {
+		return "", er.OutputWriteErr
+	}
+	return objID, nil
+}
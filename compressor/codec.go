@@ -0,0 +1,186 @@
+package compressor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+	er "github.com/mainak55512/qwe/qwerror"
+)
+
+// Compression algorithm of an object. Objects start with the codec as header byte, the values never
+// have 8 in the low nibble so they can not be confused with the first byte of a header-less zlib stream
+// written by earlier versions.
+type Codec byte
+
+const (
+	None Codec = 0x01
+	Zlib Codec = 0x02
+	Gzip Codec = 0x03
+	Zstd Codec = 0x04
+)
+
+var codecNames = map[Codec]string{
+	None: "none",
+	Zlib: "zlib",
+	Gzip: "gzip",
+	Zstd: "zstd",
+}
+
+func (c Codec) String() string {
+	if name, ok := codecNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("codec(%#x)", byte(c))
+}
+
+// Returns the codec with the supplied name
+func ParseCodec(name string) (Codec, error) {
+	for c, n := range codecNames {
+		if strings.EqualFold(n, name) {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", er.InvalidCodec, name)
+}
+
+// Returns the names of the supported codecs
+func CodecNames() []string {
+	names := make([]string, 0, len(codecNames))
+	for _, name := range codecNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Selects the codec of new objects. Content of at least LargeSize bytes is compressed with Large,
+// everything else and content of unknown size with Codec. A LargeSize of 0 disables the size class.
+type Policy struct {
+	Codec     Codec
+	Large     Codec
+	LargeSize int64
+}
+
+// Objects are zlib compressed unless the repository configures otherwise
+var DefaultPolicy = Policy{Codec: Zlib}
+
+var policy atomic.Pointer[Policy]
+
+func init() {
+	SetPolicy(DefaultPolicy)
+}
+
+// Sets the codecs used for the objects written from now on, existing objects keep their codec
+func SetPolicy(p Policy) {
+	policy.Store(&p)
+}
+
+// Returns the codecs used for new objects
+func CurrentPolicy() Policy {
+	return *policy.Load()
+}
+
+// Returns the codec for content of the supplied size, a negative size is unknown
+func (p Policy) CodecFor(size int64) Codec {
+	if p.LargeSize > 0 && size >= p.LargeSize && p.Large != 0 {
+		return p.Large
+	}
+	if p.Codec == 0 {
+		return Zlib
+	}
+	return p.Codec
+}
+
+// Returns a writer that writes the codec header and compresses to w, Close ends the compressed stream but does not close w
+func (c Codec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if _, ok := codecNames[c]; !ok {
+		return nil, fmt.Errorf("%w: %s", er.InvalidCodec, c)
+	}
+	if _, err := w.Write([]byte{byte(c)}); err != nil {
+		return nil, er.CompBufInitErr
+	}
+	var cw io.WriteCloser
+	var err error
+	switch c {
+	case None:
+		cw = nopWriteCloser{w}
+	case Zlib:
+		cw, err = zlib.NewWriterLevel(w, zlib.BestCompression)
+	case Gzip:
+		cw, err = gzip.NewWriterLevel(w, gzip.DefaultCompression)
+	case Zstd:
+		cw, err = zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	}
+	if err != nil {
+		return nil, er.CompBufInitErr
+	}
+	return cw, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// Returns a reader for an object stream, the codec is read from the header
+func newCodecReader(r io.Reader) (io.ReadCloser, error) {
+	var header [1]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, er.DecompBufInitErr
+	}
+
+	// Header-less zlib stream of an earlier version, the byte is part of the stream
+	if header[0]&0x0f == 8 {
+		zr, err := zlib.NewReader(io.MultiReader(bytes.NewReader(header[:]), r))
+		if err != nil {
+			return nil, er.DecompBufInitErr
+		}
+		return &legacyReader{zr}, nil
+	}
+
+	var rc io.ReadCloser
+	var err error
+	switch Codec(header[0]) {
+	case None:
+		rc = io.NopCloser(r)
+	case Zlib:
+		rc, err = zlib.NewReader(r)
+	case Gzip:
+		rc, err = gzip.NewReader(r)
+	case Zstd:
+		var zr *zstd.Decoder
+		if zr, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1)); err == nil {
+			rc = zr.IOReadCloser()
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", er.InvalidCodec, Codec(header[0]))
+	}
+	if err != nil {
+		return nil, er.DecompBufInitErr
+	}
+	return rc, nil
+}
+
+// Objects written by earlier versions miss the end of the zlib stream, their content is returned without an error
+type legacyReader struct {
+	io.ReadCloser
+}
+
+func (r *legacyReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
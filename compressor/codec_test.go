@@ -0,0 +1,93 @@
+package compressor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	er "github.com/mainak55512/qwe/qwerror"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	defer SetPolicy(CurrentPolicy())
+	content := bytes.Repeat([]byte("codec content\n"), 5000)
+
+	for _, name := range CodecNames() {
+		codec, err := ParseCodec(name)
+		if err != nil {
+			t.Fatalf("ParseCodec(%q) failed: %v", name, err)
+		}
+		SetPolicy(Policy{Codec: codec})
+		target := filepath.Join(t.TempDir(), "object")
+		if err := WriteFile(target, content); err != nil {
+			t.Fatalf("%s: WriteFile() failed: %v", name, err)
+		}
+		raw, _ := os.ReadFile(target)
+		if len(raw) == 0 || Codec(raw[0]) != codec {
+			t.Errorf("%s: expected the object to start with the codec header", name)
+		}
+		if codec != None && len(raw) >= len(content) {
+			t.Errorf("%s: expected the object to be compressed, got %d bytes", name, len(raw))
+		}
+		if got, err := ReadFile(target); err != nil || !bytes.Equal(got, content) {
+			t.Errorf("%s: round trip returned %d bytes, %v", name, len(got), err)
+		}
+	}
+}
+
+func TestPolicySizeClass(t *testing.T) {
+	defer SetPolicy(CurrentPolicy())
+	SetPolicy(Policy{Codec: Zlib, Large: Zstd, LargeSize: 100})
+
+	dir := t.TempDir()
+	cases := []struct {
+		size  int
+		codec Codec
+	}{
+		{99, Zlib},
+		{100, Zstd},
+	}
+	for _, c := range cases {
+		target := filepath.Join(dir, "object")
+		if err := WriteFile(target, bytes.Repeat([]byte("x"), c.size)); err != nil {
+			t.Fatalf("WriteFile() failed: %v", err)
+		}
+		raw, _ := os.ReadFile(target)
+		if Codec(raw[0]) != c.codec {
+			t.Errorf("content of %d bytes: expected %s, got %s", c.size, c.codec, Codec(raw[0]))
+		}
+	}
+
+	// Plain files report their size as well
+	src := filepath.Join(dir, "plain")
+	os.WriteFile(src, bytes.Repeat([]byte("y"), 200), 0o644)
+	file, _ := os.Open(src)
+	defer file.Close()
+	target := filepath.Join(dir, "copied")
+	if err := Copy(target, file); err != nil {
+		t.Fatalf("Copy() failed: %v", err)
+	}
+	if raw, _ := os.ReadFile(target); Codec(raw[0]) != Zstd {
+		t.Errorf("expected a large plain file to use zstd, got %s", Codec(raw[0]))
+	}
+}
+
+func TestReadLegacyAndUnknownObjects(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte("header-less\n"))
+	zw.Close()
+	if got, err := DecompressBytes(buf.Bytes()); err != nil || string(got) != "header-less\n" {
+		t.Errorf("expected the header-less zlib content, got %q, %v", got, err)
+	}
+
+	if _, err := DecompressBytes([]byte{0x7f, 1, 2, 3}); !errors.Is(err, er.InvalidCodec) {
+		t.Errorf("expected an unknown codec error, got %v", err)
+	}
+	if _, err := ParseCodec("lz4"); !errors.Is(err, er.InvalidCodec) {
+		t.Errorf("expected ParseCodec to reject lz4, got %v", err)
+	}
+}
@@ -2,78 +2,221 @@ package compressor
 
 import (
 	"bytes"
-	"compress/zlib"
-	"errors"
 	er "github.com/mainak55512/qwe/qwerror"
 	"io"
 	"os"
+	"path/filepath"
 )
 
-// Compresses the file with zlib
-func CompressFile(filePath string) error {
-	var buf bytes.Buffer
+// Returns a reader that decompresses r, the codec is taken from the header of the stream.
+// Header-less zlib objects written by earlier versions are read as well.
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	return newCodecReader(r)
+}
+
+// Returns a writer that compresses to w with the codec the current policy selects for content of unknown size,
+// Close ends the compressed stream but does not close w
+func NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return CurrentPolicy().CodecFor(-1).NewWriter(w)
+}
+
+// Opens a compressed file, the content is decompressed while it is read
+func Open(filePath string) (io.ReadCloser, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return er.CompOpenErr
+		return nil, err
 	}
-	defer file.Close()
-	zw, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	zr, err := NewReader(file)
 	if err != nil {
-		return er.CompBufInitErr
+		file.Close()
+		return nil, err
 	}
-	defer zw.Close()
+	return &fileReader{ReadCloser: zr, file: file}, nil
+}
 
-	// Copy file content to the buffer as well as compressing it
-	if _, err = io.Copy(zw, file); err != nil {
-		return er.BufCopyErr
+type fileReader struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (r *fileReader) Close() error {
+	err := r.ReadCloser.Close()
+	if fileErr := r.file.Close(); err == nil {
+		err = fileErr
 	}
-	zw.Flush()
-	com_file, err := os.Create(filePath)
+	return err
+}
+
+// Compressing writer of a file created with Create
+type Writer struct {
+	zw     io.WriteCloser
+	tmp    *os.File
+	target string
+	done   bool
+}
+
+// Creates a compressed file. The content is written to a temporary file in the same directory
+// that replaces filePath on Close, so readers never see a partial file. Abort drops the temporary file instead.
+func Create(filePath string) (*Writer, error) {
+	return create(filePath, -1)
+}
+
+// Creates a compressed file with the codec the current policy selects for content of the supplied size
+func create(filePath string, size int64) (*Writer, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp*")
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer com_file.Close()
+	zw, err := CurrentPolicy().CodecFor(size).NewWriter(tmp)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return &Writer{zw: zw, tmp: tmp, target: filePath}, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.zw.Write(p)
+}
 
-	// Copy compressed content from buffer to the file
-	if _, err = io.Copy(com_file, &buf); err != nil {
+func (w *Writer) Close() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	if err := w.zw.Close(); err != nil {
+		w.tmp.Close()
+		os.Remove(w.tmp.Name())
 		return er.BufCopyErr
 	}
+	if err := w.tmp.Close(); err != nil {
+		os.Remove(w.tmp.Name())
+		return err
+	}
+	if err := os.Chmod(w.tmp.Name(), 0644); err != nil {
+		os.Remove(w.tmp.Name())
+		return err
+	}
+	if err := os.Rename(w.tmp.Name(), w.target); err != nil {
+		os.Remove(w.tmp.Name())
+		return err
+	}
 	return nil
 }
 
-// decompresses the file using a temporary one
-func DecompressFile(filePath string) error {
-	input, err := os.Open(filePath)
+func (w *Writer) Abort() {
+	if w.done {
+		return
+	}
+	w.done = true
+	w.tmp.Close()
+	os.Remove(w.tmp.Name())
+}
+
+// Compresses everything read from src into filePath
+func Copy(filePath string, src io.Reader) error {
+	w, err := create(filePath, sizeOf(src))
 	if err != nil {
 		return err
 	}
-	defer input.Close()
+	if _, err = io.Copy(w, src); err != nil {
+		w.Abort()
+		return er.BufCopyErr
+	}
+	return w.Close()
+}
+
+// Returns the number of bytes left in src if it is known, -1 otherwise
+func sizeOf(src io.Reader) int64 {
+	switch r := src.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - pos
+	}
+	return -1
+}
+
+// Compresses content into filePath
+func WriteFile(filePath string, content []byte) error {
+	return Copy(filePath, bytes.NewReader(content))
+}
 
-	// Create zlib reader for the file
-	zr, err := zlib.NewReader(input)
+// Compresses a plain file in place, used for files that were written without this package
+func CompressFile(filePath string) error {
+	file, err := os.Open(filePath)
 	if err != nil {
-		return er.DecompBufInitErr
+		return er.CompOpenErr
 	}
-	defer zr.Close()
+	defer file.Close()
+	return Copy(filePath, file)
+}
 
-	tmpPath := filePath + ".tmp"
-	output, err := os.Create(tmpPath)
+// Decompresses a file in place, used to hand out plain copies of objects
+func DecompressFile(filePath string) error {
+	input, err := Open(filePath)
 	if err != nil {
 		return err
 	}
+	defer input.Close()
 
-	defer output.Close()
-
-	// Decompress and copy content from zlib reader to temporary file
-	if _, err = io.Copy(output, zr); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
-		os.Remove(tmpPath)
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(tmp, input); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
 		return er.BufCopyErr
 	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
 
 	// Rename temporary file with the actual output file name
-	if err = os.Rename(tmpPath, filePath); err != nil {
-		return err
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// Reads the decompressed content of a compressed file without modifying the file
+func ReadFile(filePath string) ([]byte, error) {
+	input, err := Open(filePath)
+	if err != nil {
+		return nil, err
 	}
+	defer input.Close()
 
-	return nil
+	var buf bytes.Buffer
+	if _, err = io.Copy(&buf, input); err != nil {
+		return nil, er.BufCopyErr
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompresses content that is already loaded in memory
+func DecompressBytes(content []byte) ([]byte, error) {
+	zr, err := NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var buf bytes.Buffer
+	if _, err = io.Copy(&buf, zr); err != nil {
+		return nil, er.BufCopyErr
+	}
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,93 @@
+package compressor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestStreamRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "object")
+	content := bytes.Repeat([]byte("streamed content\n"), 10000)
+
+	w, err := Create(target)
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	if _, err := io.Copy(w, bytes.NewReader(content)); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("expected the target to appear only on Close, got %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	raw, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("failed to read object: %v", err)
+	}
+	if len(raw) >= len(content) {
+		t.Errorf("expected the object to be compressed on disk, got %d bytes", len(raw))
+	}
+
+	// Concurrent readers never change the object
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := ReadFile(target)
+			if err != nil || !bytes.Equal(got, content) {
+				t.Errorf("concurrent read returned %d bytes, %v", len(got), err)
+			}
+		}()
+	}
+	wg.Wait()
+	if after, _ := os.ReadFile(target); !bytes.Equal(after, raw) {
+		t.Error("reading the object modified it")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left, got %d entries", len(entries))
+	}
+}
+
+func TestAbortKeepsTarget(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "object")
+	if err := WriteFile(target, []byte("kept")); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	w, err := Create(target)
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	w.Write([]byte("discarded"))
+	w.Abort()
+	if got, err := ReadFile(target); err != nil || string(got) != "kept" {
+		t.Errorf("expected the previous content to be kept, got %q, %v", got, err)
+	}
+}
+
+func TestReadLegacyObject(t *testing.T) {
+	// Earlier versions flushed the stream without ending it
+	var buf bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	zw.Write([]byte("legacy content\n"))
+	zw.Flush()
+
+	target := filepath.Join(t.TempDir(), "object")
+	if err := os.WriteFile(target, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write object: %v", err)
+	}
+	if got, err := ReadFile(target); err != nil || string(got) != "legacy content\n" {
+		t.Errorf("expected the legacy content, got %q, %v", got, err)
+	}
+}
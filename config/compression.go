@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	cp "github.com/mainak55512/qwe/compressor"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+)
+
+// Codecs of the objects of a repository. Files of at least LargeSize bytes are compressed with Large,
+// all other objects with Codec. Codec names are the ones of compressor.ParseCodec.
+type Compression struct {
+	Codec     string `json:"codec,omitempty"`
+	Large     string `json:"large,omitempty"`
+	LargeSize int64  `json:"large_size,omitempty"`
+}
+
+// Returns the compressor policy of the configuration
+func (c *Config) CompressionPolicy() (cp.Policy, error) {
+	policy := cp.DefaultPolicy
+	if c.Compression == nil {
+		return policy, nil
+	}
+	var err error
+	if c.Compression.Codec != "" {
+		if policy.Codec, err = cp.ParseCodec(c.Compression.Codec); err != nil {
+			return policy, err
+		}
+	}
+	if c.Compression.Large != "" && c.Compression.LargeSize > 0 {
+		if policy.Large, err = cp.ParseCodec(c.Compression.Large); err != nil {
+			return policy, err
+		}
+		policy.LargeSize = c.Compression.LargeSize
+	}
+	return policy, nil
+}
+
+// Applies the compression settings of the repository in the working directory to the objects written from now on
+func ApplyCompression() error {
+	if !utl.QweIsInWorkingDir() {
+		return nil
+	}
+	config, err := Load()
+	if err != nil {
+		return err
+	}
+	policy, err := config.CompressionPolicy()
+	if err != nil {
+		return err
+	}
+	cp.SetPolicy(policy)
+	return nil
+}
+
+// Parses a size in bytes with an optional K, M or G suffix, e.g. 512K or 64M
+func ParseSize(size string) (int64, error) {
+	multiplier := int64(1)
+	value := strings.TrimSuffix(strings.ToUpper(size), "B")
+	if n := len(value); n > 0 {
+		switch value[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		}
+		if multiplier != 1 {
+			value = value[:n-1]
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, er.CLICompressionErr
+	}
+	return n * multiplier, nil
+}
+
+// Changes the codecs of the repository, an empty codec or large codec and a negative large size keep the current setting.
+// A large size of 0 removes the size class. Existing objects keep their codec.
+func SetCompression(codec, large string, largeSize int64) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	config, err := Load()
+	if err != nil {
+		return err
+	}
+	settings := Compression{}
+	if config.Compression != nil {
+		settings = *config.Compression
+	}
+	if codec != "" {
+		if _, err = cp.ParseCodec(codec); err != nil {
+			return err
+		}
+		settings.Codec = strings.ToLower(codec)
+	}
+	if large != "" {
+		if _, err = cp.ParseCodec(large); err != nil {
+			return err
+		}
+		settings.Large = strings.ToLower(large)
+	}
+	if largeSize >= 0 {
+		settings.LargeSize = largeSize
+	}
+	if settings.LargeSize > 0 && settings.Large == "" {
+		return er.CLICompressionErr
+	}
+	if settings.LargeSize == 0 {
+		settings.Large = ""
+	}
+	config.Compression = &settings
+	if err = config.Save(); err != nil {
+		return err
+	}
+	return PrintCompression()
+}
+
+// Prints the codecs used for new objects of the repository
+func PrintCompression() error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	config, err := Load()
+	if err != nil {
+		return err
+	}
+	policy, err := config.CompressionPolicy()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Codec:", policy.Codec)
+	if policy.LargeSize > 0 {
+		fmt.Printf("Files of %d bytes or more: %s\n", policy.LargeSize, policy.Large)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+
+	cp "github.com/mainak55512/qwe/compressor"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"0":    0,
+		"4096": 4096,
+		"512K": 512 << 10,
+		"64mb": 64 << 20,
+		"2G":   2 << 30,
+		"100B": 100,
+	}
+	for input, want := range cases {
+		if got, err := ParseSize(input); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "M", "-1", "ten"} {
+		if _, err := ParseSize(input); err == nil {
+			t.Errorf("expected ParseSize(%q) to fail", input)
+		}
+	}
+}
+
+func TestCompressionPolicy(t *testing.T) {
+	config := &Config{}
+	if policy, err := config.CompressionPolicy(); err != nil || policy != cp.DefaultPolicy {
+		t.Errorf("expected the default policy without settings, got %+v, %v", policy, err)
+	}
+
+	config.Compression = &Compression{Codec: "gzip", Large: "zstd", LargeSize: 1 << 20}
+	policy, err := config.CompressionPolicy()
+	if err != nil {
+		t.Fatalf("CompressionPolicy() failed: %v", err)
+	}
+	if policy.CodecFor(10) != cp.Gzip || policy.CodecFor(1<<20) != cp.Zstd || policy.CodecFor(-1) != cp.Gzip {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+
+	config.Compression.Codec = "brotli"
+	if _, err := config.CompressionPolicy(); err == nil {
+		t.Error("expected an unknown codec to be rejected")
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+)
+
+const configPath = ".qwe/_config.qwe"
+
+// Settings of a repository, stored as plain JSON so they can be edited by hand
+type Config struct {
+	Remotes     map[string]string `json:"remotes,omitempty"`     // remote name -> path of the remote .qwe directory
+	Compression *Compression      `json:"compression,omitempty"` // codecs of new objects, zlib if unset
+}
+
+// Reads the configuration of the repository in the working directory, a missing file is an empty configuration
+func Load() (*Config, error) {
+	config := &Config{}
+	if utl.FileExists(configPath) {
+		content, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, er.TrackerAccessErr
+		}
+		if err = json.Unmarshal(content, config); err != nil {
+			return nil, er.TrackerParseErr
+		}
+	}
+	if config.Remotes == nil {
+		config.Remotes = make(map[string]string)
+	}
+	return config, nil
+}
+
+// Writes the configuration of the repository in the working directory
+func (c *Config) Save() error {
+	content, err := json.MarshalIndent(c, "", " ")
+	if err != nil {
+		return er.TrackerWriteErr
+	}
+	if err = os.WriteFile(configPath, content, 0644); err != nil {
+		return er.TrackerWriteErr
+	}
+	return nil
+}
@@ -1,8 +1,6 @@
 package diff
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -11,7 +9,6 @@ import (
 	"time"
 
 	bh "github.com/mainak55512/qwe/binaryhandler"
-	cp "github.com/mainak55512/qwe/compressor"
 	er "github.com/mainak55512/qwe/qwerror"
 	utl "github.com/mainak55512/qwe/qweutils"
 	res "github.com/mainak55512/qwe/reconstruct"
@@ -19,22 +16,61 @@ import (
 )
 
 type Changes struct {
-	Prev string
-	Curr string
+	Prev string `json:"prev"`
+	Curr string `json:"curr"`
 }
 
-// Determines the difference between two version of the file
+// Difference between two versions of a file, binary files only report whether the content changed
+type Result struct {
+	Binary  bool      `json:"binary"`
+	Changed bool      `json:"changed"`
+	Changes []Changes `json:"changes"`
+}
+
+// Prints the difference
+func (r *Result) Print() {
+	if r.Binary {
+		if r.Changed {
+			fmt.Println("File content changed!")
+		} else {
+			fmt.Println("File content is same!")
+		}
+		return
+	}
+	if len(r.Changes) == 0 {
+		fmt.Println("No Change!")
+		return
+	}
+	fmt.Printf("===Start Diff view===\n\n")
+	for _, elem := range r.Changes {
+		fmt.Println(elem.Prev + "\n" + elem.Curr)
+		fmt.Println()
+	}
+	fmt.Printf("\n===End of Diff===")
+}
+
+// Prints the difference between two version of the file
 func Diff(filePath, commitID1Str, commitID2Str string) error {
+	result, err := Compare(filePath, commitID1Str, commitID2Str)
+	if err != nil {
+		return err
+	}
+	result.Print()
+	return nil
+}
+
+// Determines the difference between two version of the file
+func Compare(filePath, commitID1Str, commitID2Str string) (*Result, error) {
 
 	// Only allow if both are either empty or non-empty
 	if !((commitID1Str == "") == (commitID2Str == "")) {
-		return fmt.Errorf("Argument number missmatch")
+		return nil, fmt.Errorf("Argument number missmatch")
 	}
 
 	// Get details from _tracker.qwe
 	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	fileId := utl.Hasher(filePath)
@@ -43,7 +79,7 @@ func Diff(filePath, commitID1Str, commitID2Str string) error {
 	// Check if file is being tracked
 	val, ok := tracker[fileId]
 	if !ok {
-		return er.FileNotTracked
+		return nil, er.FileNotTracked
 	}
 
 	// Will run if no commit id is passed or both commit id is passed and first one is 'uncommitted'
@@ -54,83 +90,27 @@ func Diff(filePath, commitID1Str, commitID2Str string) error {
 		if commitID2Str != "" {
 			commitID, err := strconv.Atoi(commitID2Str)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			if strings.HasPrefix(val.Base, "_bin_") {
-
-				target_content, err := os.Create(target)
-				if err != nil {
-					return err
-				}
-				defer target_content.Close()
-				current_content, err := os.Open(".qwe/_object/" + val.Versions[commitID].UID)
-				if err != nil {
-					return err
-				}
-				defer current_content.Close()
-				buf := make([]byte, 1024)
-				_, err = io.CopyBuffer(target_content, current_content, buf)
-				if err != nil {
-					return err
-				}
-				if err := cp.DecompressFile(target); err != nil {
-					os.Remove(target)
-					return err
-				}
-				isEq, err := bh.CheckBinDiff(target, filePath)
+				isEq, err := sameObject(val.Versions[commitID].UID, filePath)
 				if err != nil {
-					os.Remove(target)
-					return err
-				}
-				if isEq {
-					fmt.Println("File content is same!")
-				} else {
-					fmt.Println("File content changed!")
+					return nil, err
 				}
-				os.Remove(target)
-				return nil
-
+				return &Result{Binary: true, Changed: !isEq}, nil
 			}
 			// Reconstruct till the specified commit id
 			if err = res.Reconstruct(val, target, commitID); err != nil {
-				return err
+				return nil, err
 			}
 		} else {
 			if strings.HasPrefix(val.Base, "_bin_") {
-
-				target_content, err := os.Create(target)
-				if err != nil {
-					return err
-				}
-				defer target_content.Close()
-				current_content, err := os.Open(".qwe/_object/" + val.Current)
-				if err != nil {
-					return err
-				}
-				defer current_content.Close()
-				buf := make([]byte, 1024)
-				_, err = io.CopyBuffer(target_content, current_content, buf)
+				isEq, err := sameObject(val.Current, filePath)
 				if err != nil {
-					return err
+					return nil, err
 				}
-				if err := cp.DecompressFile(target); err != nil {
-					os.Remove(target)
-					return err
-				}
-				isEq, err := bh.CheckBinDiff(target, filePath)
-				if err != nil {
-					os.Remove(target)
-					return err
-				}
-				if isEq {
-					fmt.Println("File content is same!")
-				} else {
-					fmt.Println("File content changed!")
-				}
-				os.Remove(target)
-				return nil
-
+				return &Result{Binary: true, Changed: !isEq}, nil
 			}
 			// Reconstruct till the current version
 			var commitID int = -1
@@ -140,7 +120,7 @@ func Diff(filePath, commitID1Str, commitID2Str string) error {
 				}
 			}
 			if err = res.Reconstruct(val, target, commitID); err != nil {
-				return err
+				return nil, err
 			}
 		}
 
@@ -148,55 +128,23 @@ func Diff(filePath, commitID1Str, commitID2Str string) error {
 		// with the latest committed version or with the version specified by the commitID2Str
 		new_file, err := os.Open(filePath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		defer new_file.Close()
 
 		current_file, err := os.Open(target)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		current_scanner := bufio.NewScanner(current_file)
-
-		new_scanner := bufio.NewScanner(new_file)
-
-		var diff_content []Changes
-
 		// Check the differences
-		line := 0
-		for new_scanner.Scan() {
-			line++
-			current_scanner.Scan()
-			if !bytes.Equal(current_scanner.Bytes(), new_scanner.Bytes()) {
-				diff_content = append(diff_content, Changes{
-					Prev: fmt.Sprintf("- %d %s", line, current_scanner.Text()),
-					Curr: fmt.Sprintf("+ %d %s", line, new_scanner.Text()),
-				})
-			}
-		}
-		for current_scanner.Scan() {
-			line++
-			if !bytes.Equal(current_scanner.Bytes(), []byte("")) {
-				diff_content = append(diff_content, Changes{
-					Prev: fmt.Sprintf("+ %d %s", line, current_scanner.Text()),
-					Curr: fmt.Sprintf("- %d %s", line, ""),
-				})
-			}
-		}
-
+		diff_content, err := compareText(current_file, new_file)
 		current_file.Close()
 		os.Remove(target)
-		if len(diff_content) == 0 {
-			fmt.Println("No Change!")
-		} else {
-			fmt.Printf("===Start Diff view===\n\n")
-			for _, elem := range diff_content {
-				fmt.Println(elem.Prev + "\n" + elem.Curr)
-				fmt.Println()
-			}
-			fmt.Printf("\n===End of Diff===")
+		if err != nil {
+			return nil, err
 		}
+		return &Result{Changed: len(diff_content) > 0, Changes: diff_content}, nil
 	} else {
 
 		// This part will execute if both commitIDs are supplied through the command line
@@ -204,137 +152,96 @@ func Diff(filePath, commitID1Str, commitID2Str string) error {
 		if commitID1Str != "" {
 			commit1, err = strconv.Atoi(commitID1Str)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 		if commitID2Str != "" {
 			commit2, err = strconv.Atoi(commitID2Str)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 		src := ".qwe/_object/_diff_src_" + fileObjectId
 		dest := ".qwe/_object/_diff_dest_" + fileObjectId
 
 		if strings.HasPrefix(val.Base, "_bin_") {
-			src_content, err := os.Create(src)
-			if err != nil {
-				return err
-			}
-			defer src_content.Close()
-			dest_content, err := os.Create(dest)
-			if err != nil {
-				return err
-			}
-			defer dest_content.Close()
-			current_src_content, err := os.Open(".qwe/_object/" + val.Versions[commit1].UID)
-			if err != nil {
-				return err
-			}
-			defer current_src_content.Close()
-			current_dest_content, err := os.Open(".qwe/_object/" + val.Versions[commit2].UID)
+			isEq, err := sameObjects(val.Versions[commit1].UID, val.Versions[commit2].UID)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			defer current_dest_content.Close()
-			buf := make([]byte, 1024)
-			_, err = io.CopyBuffer(src_content, current_src_content, buf)
-			if err != nil {
-				return err
-			}
-			_, err = io.CopyBuffer(dest_content, current_dest_content, buf)
-			if err != nil {
-				return err
-			}
-			if err := cp.DecompressFile(src); err != nil {
-				os.Remove(src)
-				os.Remove(dest)
-				return err
-			}
-			if err := cp.DecompressFile(dest); err != nil {
-				os.Remove(src)
-				os.Remove(dest)
-				return err
-			}
-			isEq, err := bh.CheckBinDiff(src, dest)
-			if err != nil {
-				os.Remove(src)
-				os.Remove(dest)
-				return err
-			}
-			if isEq {
-				fmt.Println("File content is same!")
-			} else {
-				fmt.Println("File content changed!")
-			}
-			os.Remove(src)
-			os.Remove(dest)
-			return nil
+			return &Result{Binary: true, Changed: !isEq}, nil
 		}
 		// reconstruct till first commitID
 		if err = res.Reconstruct(val, src, commit1); err != nil {
-			return err
+			return nil, err
 		}
 
 		// Reconstruct till second commitID
 		if err = res.Reconstruct(val, dest, commit2); err != nil {
-			return err
+			return nil, err
 		}
 		new_file, err := os.Open(dest)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		defer new_file.Close()
 
 		current_file, err := os.Open(src)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		// defer current_file.Close()
-		current_scanner := bufio.NewScanner(current_file)
-
-		new_scanner := bufio.NewScanner(new_file)
-
-		var diff_content []Changes
 
 		// Compare for changes
-		line := 0
-		for new_scanner.Scan() {
-			line++
-			current_scanner.Scan()
-			if !bytes.Equal(current_scanner.Bytes(), new_scanner.Bytes()) {
-				diff_content = append(diff_content, Changes{
-					Prev: fmt.Sprintf("- %d %s", line, current_scanner.Text()),
-					Curr: fmt.Sprintf("+ %d %s", line, new_scanner.Text()),
-				})
-			}
-		}
-		for current_scanner.Scan() {
-			line++
-			if !bytes.Equal(current_scanner.Bytes(), []byte("")) {
-				diff_content = append(diff_content, Changes{
-					Prev: fmt.Sprintf("+ %d %s", line, current_scanner.Text()),
-					Curr: fmt.Sprintf("- %d %s", line, ""),
-				})
-			}
-		}
-		// diff_content = fmt.Sprintf("%d\n%s", line, diff_content)
+		diff_content, err := compareText(current_file, new_file)
 		current_file.Close()
-
-		// Remove temporary files
 		os.Remove(src)
 		os.Remove(dest)
-
-		if len(diff_content) == 0 {
-			fmt.Println("No Change!")
-		} else {
-			fmt.Printf("===Start Diff view===\n\n")
-			for _, elem := range diff_content {
-				fmt.Println(elem.Prev + "\n" + elem.Curr)
-				fmt.Println()
-			}
-			fmt.Printf("\n===End of Diff===")
+		if err != nil {
+			return nil, err
 		}
+
+		return &Result{Changed: len(diff_content) > 0, Changes: diff_content}, nil
 	}
-	return nil
+}
+
+// Compares two versions of a text file line by line, lines removed from the end are listed after the changed lines
+func compareText(prev, next io.Reader) ([]Changes, error) {
+	var diff_content []Changes
+	_, err := utl.CompareLines(prev, next, func(change utl.LineChange, line int, prevLine, nextLine string) {
+		if change == utl.LineRemoved {
+			diff_content = append(diff_content, Changes{
+				Prev: fmt.Sprintf("+ %d %s", line, prevLine),
+				Curr: fmt.Sprintf("- %d %s", line, ""),
+			})
+			return
+		}
+		diff_content = append(diff_content, Changes{
+			Prev: fmt.Sprintf("- %d %s", line, prevLine),
+			Curr: fmt.Sprintf("+ %d %s", line, nextLine),
+		})
+	})
+	return diff_content, err
+}
+
+// Compares a binary object with a working file without writing a plain copy of the object
+func sameObject(objID, filePath string) (bool, error) {
+	return bh.MatchesFile(objID, filePath)
+}
+
+// Compares two binary objects
+func sameObjects(objID1, objID2 string) (bool, error) {
+	if objID1 == objID2 {
+		return true, nil
+	}
+	object1, err := bh.Open(objID1)
+	if err != nil {
+		return false, err
+	}
+	defer object1.Close()
+	object2, err := bh.Open(objID2)
+	if err != nil {
+		return false, err
+	}
+	defer object2.Close()
+	return bh.SameContent(object1, object2)
 }
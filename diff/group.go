@@ -0,0 +1,188 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	cm "github.com/mainak55512/qwe/commit"
+	res "github.com/mainak55512/qwe/reconstruct"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// Status of a file between two group versions
+const (
+	FileAdded     = "added"
+	FileRemoved   = "removed"
+	FileModified  = "modified"
+	FileUnchanged = "unchanged"
+)
+
+// Difference of a single file of a group, Result is only set for modified files
+type FileChange struct {
+	File   string  `json:"file"`
+	Status string  `json:"status"`
+	Result *Result `json:"result,omitempty"`
+}
+
+// Difference between two versions of a group, or a group version and the working files
+type GroupResult struct {
+	Files []FileChange `json:"files"`
+}
+
+// Prints the difference file by file, followed by a summary
+func (g *GroupResult) Print() {
+	counts := make(map[string]int)
+	for _, f := range g.Files {
+		counts[f.Status]++
+		if f.Status == FileUnchanged {
+			continue
+		}
+		fmt.Printf("%s: %s\n", strings.ToUpper(f.Status[:1])+f.Status[1:], f.File)
+		if f.Result != nil {
+			f.Result.Print()
+			fmt.Println()
+		}
+	}
+	fmt.Printf("%d added, %d removed, %d modified, %d unchanged\n",
+		counts[FileAdded], counts[FileRemoved], counts[FileModified], counts[FileUnchanged])
+}
+
+// Prints the difference between two versions of a group, see GroupCompare
+func GroupDiff(groupName string, from, to int, working bool) error {
+	result, err := GroupCompare(groupName, from, to, working)
+	if err != nil {
+		return err
+	}
+	result.Print()
+	return nil
+}
+
+// A file of one side of a group comparison
+type groupFile struct {
+	name    string
+	binary  bool
+	content func() ([]byte, error)
+}
+
+// Returns the files of a group version, commitNumber -1 is the current version.
+// Files whose commit failed or is no longer part of their history are left out.
+func versionFiles(groupName string, commitNumber int, tracker tr.TrackerSchema) (map[string]groupFile, error) {
+	info, err := cm.GroupVersion(groupName, commitNumber)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]groupFile)
+	for fileID, commitID := range info.Commits {
+		val := tracker[fileID]
+		files[fileID] = groupFile{
+			name:   info.Version.Files[fileID].FileName,
+			binary: strings.HasPrefix(val.Base, "_bin_"),
+			content: func() ([]byte, error) {
+				return res.Content(val, commitID)
+			},
+		}
+	}
+	return files, nil
+}
+
+// Returns the working copies of the files of the current group version, missing files are left out
+func workingFiles(groupName string, tracker tr.TrackerSchema) (map[string]groupFile, error) {
+	info, err := cm.GroupVersion(groupName, -1)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]groupFile)
+	for fileID, f := range info.Version.Files {
+		if _, err := os.Stat(f.FileName); err != nil {
+			continue
+		}
+		fileName := f.FileName
+		files[fileID] = groupFile{
+			name:   fileName,
+			binary: strings.HasPrefix(tracker[fileID].Base, "_bin_"),
+			content: func() ([]byte, error) {
+				return os.ReadFile(fileName)
+			},
+		}
+	}
+	return files, nil
+}
+
+// Determines the difference between the group versions from and to, -1 is the current version.
+// If working is set, the version from is compared with the working files of the group instead.
+func GroupCompare(groupName string, from, to int, working bool) (*GroupResult, error) {
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := versionFiles(groupName, from, tracker)
+	if err != nil {
+		return nil, err
+	}
+	var next map[string]groupFile
+	if working {
+		next, err = workingFiles(groupName, tracker)
+	} else {
+		next, err = versionFiles(groupName, to, tracker)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GroupResult{}
+	for fileID, p := range prev {
+		n, ok := next[fileID]
+		if !ok {
+			result.Files = append(result.Files, FileChange{File: p.name, Status: FileRemoved})
+			continue
+		}
+		change, err := compareFile(p, n)
+		if err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, change)
+	}
+	for fileID, n := range next {
+		if _, ok := prev[fileID]; !ok {
+			result.Files = append(result.Files, FileChange{File: n.name, Status: FileAdded})
+		}
+	}
+	sort.Slice(result.Files, func(i, j int) bool {
+		return result.Files[i].File < result.Files[j].File
+	})
+	return result, nil
+}
+
+func compareFile(prev, next groupFile) (FileChange, error) {
+	prevContent, err := prev.content()
+	if err != nil {
+		return FileChange{}, err
+	}
+	nextContent, err := next.content()
+	if err != nil {
+		return FileChange{}, err
+	}
+
+	var result *Result
+	if prev.binary {
+		result = &Result{Binary: true, Changed: !bytes.Equal(prevContent, nextContent)}
+	} else {
+		changes, err := compareLines(prevContent, nextContent)
+		if err != nil {
+			return FileChange{}, err
+		}
+		result = &Result{Changed: len(changes) > 0, Changes: changes}
+	}
+	if !result.Changed {
+		return FileChange{File: next.name, Status: FileUnchanged}, nil
+	}
+	return FileChange{File: next.name, Status: FileModified, Result: result}, nil
+}
+
+// Compares two text contents line by line, the same way Compare does for files
+func compareLines(prev, next []byte) ([]Changes, error) {
+	return compareText(bytes.NewReader(prev), bytes.NewReader(next))
+}
@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"os"
+	"testing"
+
+	cm "github.com/mainak55512/qwe/commit"
+	in "github.com/mainak55512/qwe/initializer"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for filePath, content := range files {
+		if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+}
+
+func statuses(result *GroupResult) map[string]string {
+	got := make(map[string]string)
+	for _, f := range result.Files {
+		got[f.File] = f.Status
+	}
+	return got
+}
+
+func TestGroupCompare(t *testing.T) {
+	_, cleanup := initQwe(t)
+	defer cleanup()
+
+	writeFiles(t, map[string]string{"a.txt": "one\n", "b.txt": "keep\n", "logo.bin": "\x00\x01logo"})
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	if err := tr.StartGroupTracking("docs", []string{"a.txt", "b.txt", "logo.bin"}); err != nil {
+		t.Fatalf("failed to track files in group: %v", err)
+	}
+	writeFiles(t, map[string]string{"a.txt": "two\n", "logo.bin": "\x00\x01new"})
+	if err := cm.CommitGroup("docs", "Change a and logo", false, false); err != nil {
+		t.Fatalf("failed to commit group: %v", err)
+	}
+	writeFiles(t, map[string]string{"c.txt": "new\n"})
+	if err := tr.StartGroupTracking("docs", []string{"c.txt"}); err != nil {
+		t.Fatalf("failed to add file to group: %v", err)
+	}
+
+	result, err := GroupCompare("docs", 0, 1, false)
+	if err != nil {
+		t.Fatalf("GroupCompare() failed: %v", err)
+	}
+	want := map[string]string{"a.txt": FileModified, "b.txt": FileUnchanged, "c.txt": FileAdded, "logo.bin": FileModified}
+	got := statuses(result)
+	for file, status := range want {
+		if got[file] != status {
+			t.Errorf("%s: expected %s, got %q", file, status, got[file])
+		}
+	}
+	for _, f := range result.Files {
+		switch f.File {
+		case "a.txt":
+			if f.Result == nil || len(f.Result.Changes) != 1 || f.Result.Changes[0].Curr != "+ 1 two" {
+				t.Errorf("expected one changed line in a.txt, got %+v", f.Result)
+			}
+		case "logo.bin":
+			if f.Result == nil || !f.Result.Binary {
+				t.Errorf("expected a binary summary for logo.bin, got %+v", f.Result)
+			}
+		}
+	}
+
+	// The current version against the working files
+	writeFiles(t, map[string]string{"b.txt": "changed\n"})
+	if err := os.Remove("c.txt"); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	result, err = GroupCompare("docs", -1, -1, true)
+	if err != nil {
+		t.Fatalf("GroupCompare() failed: %v", err)
+	}
+	want = map[string]string{"a.txt": FileUnchanged, "b.txt": FileModified, "c.txt": FileRemoved, "logo.bin": FileUnchanged}
+	got = statuses(result)
+	for file, status := range want {
+		if got[file] != status {
+			t.Errorf("%s: expected %s, got %q", file, status, got[file])
+		}
+	}
+
+	if _, err := GroupCompare("docs", 0, 5, false); err == nil {
+		t.Error("expected error for an invalid commit number")
+	}
+}
+
+func TestGroupStatusOf(t *testing.T) {
+	_, cleanup := initQwe(t)
+	defer cleanup()
+
+	writeFiles(t, map[string]string{"a.txt": "one\n", "b.txt": "keep\n", "c.txt": "gone\n", "d.txt": "one\n", "logo.bin": "\x00\x01logo"})
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	if err := tr.StartGroupTracking("docs", []string{"a.txt", "b.txt", "c.txt", "d.txt", "logo.bin"}); err != nil {
+		t.Fatalf("failed to track files in group: %v", err)
+	}
+	writeFiles(t, map[string]string{"a.txt": "two\n", "d.txt": "two\n", "logo.bin": "\x00\x01new"})
+	if err := os.Remove("c.txt"); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	if _, _, err := cm.CommitUnit("d.txt", "Commit d outside the group"); err != nil {
+		t.Fatalf("failed to commit file: %v", err)
+	}
+	if err := tr.StopGroupTracking("docs", "b.txt"); err != nil {
+		t.Fatalf("failed to untrack file from group: %v", err)
+	}
+
+	_, statuses, err := GroupStatusOf("docs")
+	if err != nil {
+		t.Fatalf("GroupStatusOf() failed: %v", err)
+	}
+	want := map[string]FileStatus{
+		"a.txt":    {File: "a.txt", Status: FileModified, GroupCommit: -2, CheckedOut: -2},
+		"b.txt":    {File: "b.txt", Status: FileUnchanged, GroupCommit: -2, CheckedOut: -2, Untracked: true},
+		"c.txt":    {File: "c.txt", Status: FileMissing, GroupCommit: -2, CheckedOut: -2},
+		"d.txt":    {File: "d.txt", Status: FileModified, GroupCommit: -2, CheckedOut: 0, Diverged: true},
+		"logo.bin": {File: "logo.bin", Status: FileBinaryChanged, GroupCommit: -2, CheckedOut: -2},
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("expected %d files, got %+v", len(want), statuses)
+	}
+	for _, s := range statuses {
+		if s != want[s.File] {
+			t.Errorf("%s: expected %+v, got %+v", s.File, want[s.File], s)
+		}
+	}
+}
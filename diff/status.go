@@ -0,0 +1,156 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	tw "text/tabwriter"
+
+	cm "github.com/mainak55512/qwe/commit"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	res "github.com/mainak55512/qwe/reconstruct"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// Status of a working file compared with the current group version, see also FileModified and FileUnchanged
+const (
+	FileMissing       = "missing"
+	FileBinaryChanged = "binary changed"
+	FileUnavailable   = "unavailable" // the group version refers to a commit that is not part of the file history
+)
+
+// State of a file of the current group version. CheckedOut is the commit of the file that is checked out,
+// Diverged is set if it is not the commit the group refers to. Untracked files are left out of the next group commit.
+type FileStatus struct {
+	File        string `json:"file"`
+	Status      string `json:"status"`
+	GroupCommit int    `json:"group_commit"`
+	CheckedOut  int    `json:"checked_out"`
+	Diverged    bool   `json:"diverged"`
+	Untracked   bool   `json:"untracked"`
+}
+
+// Compares every file of the current group version with its working file
+func GroupStatusOf(groupName string) (*cm.GroupVersionInfo, []FileStatus, error) {
+	if !utl.QweIsInWorkingDir() {
+		return nil, nil, er.RepoNotFound
+	}
+	info, err := cm.GroupVersion(groupName, -1)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracker, groupTracker, err := trackers()
+	if err != nil {
+		return nil, nil, err
+	}
+	gr := groupTracker[utl.Hasher(groupName)]
+
+	var statuses []FileStatus
+	for fileID, f := range info.Version.Files {
+		val, ok := tracker[fileID]
+		if !ok {
+			continue
+		}
+		commitID, ok := info.Commits[fileID]
+		current, _ := val.CommitID(val.Current)
+		status := FileStatus{
+			File:        f.FileName,
+			GroupCommit: commitID,
+			CheckedOut:  current,
+			Diverged:    ok && current != commitID,
+			Untracked:   slices.Contains(gr.Untracked, fileID),
+		}
+		switch {
+		case !utl.FileExists(f.FileName):
+			status.Status = FileMissing
+		case !ok:
+			status.Status = FileUnavailable
+		default:
+			if status.Status, err = workingStatus(val, commitID, f.FileName); err != nil {
+				return nil, nil, err
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].File < statuses[j].File
+	})
+	return info, statuses, nil
+}
+
+func trackers() (tr.TrackerSchema, tr.GroupTrackerSchema, error) {
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tracker, groupTracker, nil
+}
+
+// Compares a working file with a commit of the file
+func workingStatus(val tr.Tracker, commitID int, filePath string) (string, error) {
+	recorded, err := res.Content(val, commitID)
+	if err != nil {
+		return "", err
+	}
+	working, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(val.Base, "_bin_") {
+		if bytes.Equal(recorded, working) {
+			return FileUnchanged, nil
+		}
+		return FileBinaryChanged, nil
+	}
+	changes, err := compareLines(recorded, working)
+	if err != nil {
+		return "", err
+	}
+	if len(changes) > 0 {
+		return FileModified, nil
+	}
+	return FileUnchanged, nil
+}
+
+func commitName(commitID int) string {
+	if commitID == res.BaseVersion {
+		return "base"
+	}
+	return fmt.Sprint(commitID)
+}
+
+// Prints which files of the current group version differ from their working files
+func GroupStatus(groupName string) error {
+	info, statuses, err := GroupStatusOf(groupName)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Group %s at commit %d\n\n", info.Name, info.CommitID)
+	if len(statuses) == 0 {
+		fmt.Println("No files tracked in the group")
+		return nil
+	}
+
+	w := new(tw.Writer)
+	w.Init(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range statuses {
+		var notes []string
+		if s.Diverged {
+			notes = append(notes, fmt.Sprintf("checked out commit %s, group refers to commit %s", commitName(s.CheckedOut), commitName(s.GroupCommit)))
+		}
+		if s.Untracked {
+			notes = append(notes, "left out of the next group commit")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.File, s.Status, strings.Join(notes, ", "))
+	}
+	w.Flush()
+	return nil
+}
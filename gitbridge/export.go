@@ -0,0 +1,220 @@
+package gitbridge
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	cm "github.com/mainak55512/qwe/commit"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	res "github.com/mainak55512/qwe/reconstruct"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// Writes a git fast-import stream
+type streamWriter struct {
+	w        *bufio.Writer
+	branch   string
+	mark     int
+	lastTime time.Time
+	blobs    map[string]int
+	parent   int
+}
+
+func (s *streamWriter) nextMark() int {
+	s.mark++
+	return s.mark
+}
+
+func (s *streamWriter) data(content []byte) {
+	fmt.Fprintf(s.w, "data %d\n", len(content))
+	s.w.Write(content)
+	s.w.WriteString("\n")
+}
+
+// Emits a blob once per object, returns its mark
+func (s *streamWriter) blob(key string, content []byte) int {
+	if mark, ok := s.blobs[key]; ok {
+		return mark
+	}
+	mark := s.nextMark()
+	s.blobs[key] = mark
+	fmt.Fprintf(s.w, "blob\nmark :%d\n", mark)
+	s.data(content)
+	return mark
+}
+
+// Converts a tracker time stamp to a commit time, commits without time stamp reuse the previous commit time
+func (s *streamWriter) commitTime(timeStamp string) time.Time {
+	t, err := time.ParseInLocation(cm.TimeStampLayout, timeStamp, time.Local)
+	if err != nil {
+		if s.lastTime.IsZero() {
+			return time.Now()
+		}
+		return s.lastTime
+	}
+	s.lastTime = t
+	return t
+}
+
+// Emits a commit replacing the whole tree with the supplied path -> blob mark entries
+func (s *streamWriter) commit(message, author, timeStamp string, files map[string]int) {
+	if author == "" {
+		author = "qwe"
+	}
+	t := s.commitTime(timeStamp)
+	mark := s.nextMark()
+	signature := fmt.Sprintf("%s <> %d %s", author, t.Unix(), t.Format("-0700"))
+
+	fmt.Fprintf(s.w, "commit refs/heads/%s\nmark :%d\n", s.branch, mark)
+	fmt.Fprintf(s.w, "author %s\ncommitter %s\n", signature, signature)
+	s.data([]byte(message + "\n"))
+	if s.parent != 0 {
+		fmt.Fprintf(s.w, "from :%d\n", s.parent)
+	}
+	s.w.WriteString("deleteall\n")
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(s.w, "M 100644 :%d %s\n", files[path], gitPath(path))
+	}
+	s.w.WriteString("\n")
+	s.parent = mark
+}
+
+// Converts a tracked file path to a path inside the git tree
+func treePath(filePath string) string {
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(filePath)), "./")
+}
+
+// Converts a tracked file path to a path of a file command, quoted if needed
+func gitPath(filePath string) string {
+	path := treePath(filePath)
+	if strings.ContainsAny(path, "\"\n") || strings.HasPrefix(path, " ") {
+		return fmt.Sprintf("%q", path)
+	}
+	return path
+}
+
+// Returns the object id of a file version, BaseVersion returns the base object
+func objectID(val tr.Tracker, commitID int) string {
+	if commitID == res.BaseVersion {
+		return val.Base
+	}
+	return val.Versions[commitID].UID
+}
+
+// Writes the history of a tracked file or of a group as a git fast-import stream.
+// A file produces a commit for its base version and one for every commit, a group produces one commit per group version.
+func ExportGit(target, output, branch string) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if output != "" && output != "-" {
+		file, err := os.Create(output)
+		if err != nil {
+			return er.OutputWriteErr
+		}
+		defer file.Close()
+		out = file
+	}
+	if branch == "" {
+		branch = "master"
+	}
+
+	s := &streamWriter{
+		w:      bufio.NewWriter(out),
+		branch: branch,
+		blobs:  make(map[string]int),
+	}
+
+	if val, ok := tracker[utl.Hasher(target)]; ok {
+		err = exportFile(s, target, val)
+	} else {
+		_, groupTracker, err2 := tr.GetTracker(tr.GroupTrackerType)
+		if err2 != nil {
+			return err2
+		}
+		gr, ok := groupTracker[utl.Hasher(target)]
+		if !ok {
+			return er.FileNotTracked
+		}
+		err = exportGroup(s, gr, tracker)
+	}
+	if err != nil {
+		return err
+	}
+	if err = s.w.Flush(); err != nil {
+		return er.OutputWriteErr
+	}
+	if output != "" && output != "-" {
+		fmt.Println("Exported", target, "to", output)
+	}
+	return nil
+}
+
+func exportFile(s *streamWriter, filePath string, val tr.Tracker) error {
+	content, err := res.Content(val, res.BaseVersion)
+	if err != nil {
+		return err
+	}
+	timeStamp := ""
+	if len(val.Versions) > 0 {
+		timeStamp = val.Versions[0].TimeStamp
+	}
+	s.commit("Base version of "+filePath, "", timeStamp, map[string]int{
+		filePath: s.blob(val.Base, content),
+	})
+
+	for i, v := range val.Versions {
+		content, err := res.Content(val, i)
+		if err != nil {
+			return err
+		}
+		s.commit(v.CommitMessage, v.Author, v.TimeStamp, map[string]int{
+			filePath: s.blob(v.UID, content),
+		})
+	}
+	return nil
+}
+
+func exportGroup(s *streamWriter, gr tr.GroupTracker, tracker tr.TrackerSchema) error {
+	for _, k := range gr.VersionOrder {
+		version := gr.Versions[k]
+		files := make(map[string]int)
+		for fileID, f := range version.Files {
+			val, ok := tracker[fileID]
+			if !ok {
+				continue
+			}
+			commitID, ok := val.CommitID(f.UID)
+			if !ok {
+				continue
+			}
+			content, err := res.Content(val, commitID)
+			if err != nil {
+				return err
+			}
+			files[f.FileName] = s.blob(objectID(val, commitID), content)
+		}
+		s.commit(version.CommitMessage, version.Author, version.TimeStamp, files)
+	}
+	return nil
+}
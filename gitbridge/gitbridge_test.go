@@ -0,0 +1,144 @@
+package gitbridge
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	cm "github.com/mainak55512/qwe/commit"
+	cp "github.com/mainak55512/qwe/compressor"
+	in "github.com/mainak55512/qwe/initializer"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte("one\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := tr.StartTracking("notes.txt"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	t.Setenv("QWE_AUTHOR", "alice")
+	for _, c := range []struct{ content, message string }{
+		{"one\ntwo\n", "Add two"},
+		{"one\n2\nthree\n", "Rewrite two, add three"},
+	} {
+		if err := os.WriteFile("notes.txt", []byte(c.content), 0o644); err != nil {
+			t.Fatalf("failed to modify file: %v", err)
+		}
+		if _, _, err := cm.CommitUnit("notes.txt", c.message); err != nil {
+			t.Fatalf("failed to commit file: %v", err)
+		}
+	}
+
+	if err := ExportGit("notes.txt", "notes.fi", ""); err != nil {
+		t.Fatalf("ExportGit() failed: %v", err)
+	}
+
+	if err := os.WriteFile("copy.txt", []byte("local\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := ImportGit("copy.txt", "notes.fi", "notes.txt", false); err == nil {
+		t.Fatal("expected import to refuse overwriting a different file")
+	}
+	// The import is on disk once it reports success, even though the command defers its writes
+	tr.DeferWrites()
+	t.Cleanup(func() { tr.FlushSession() })
+	if err := ImportGit("copy.txt", "notes.fi", "notes.txt", true); err != nil {
+		t.Fatalf("ImportGit() failed: %v", err)
+	}
+	raw, err := cp.ReadFile(".qwe/_tracker.qwe")
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	if !strings.Contains(string(raw), utl.Hasher("copy.txt")) {
+		t.Error("expected the imported history to be written to disk")
+	}
+
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	original := tracker[utl.Hasher("notes.txt")]
+	imported := tracker[utl.Hasher("copy.txt")]
+	if len(imported.Versions) != len(original.Versions) {
+		t.Fatalf("expected %d commits, got %d", len(original.Versions), len(imported.Versions))
+	}
+	for i, v := range imported.Versions {
+		o := original.Versions[i]
+		if v.CommitMessage != o.CommitMessage || v.Author != o.Author || v.TimeStamp != o.TimeStamp {
+			t.Errorf("commit %d: expected %+v, got %+v", i, o, v)
+		}
+	}
+
+	content, err := os.ReadFile("copy.txt")
+	if err != nil {
+		t.Fatalf("failed to read imported file: %v", err)
+	}
+	if string(content) != "one\n2\nthree\n" {
+		t.Errorf("unexpected content of imported file: %q", content)
+	}
+
+	if err := ImportGit("copy.txt", "notes.fi", "notes.txt", true); err == nil {
+		t.Error("expected import of a tracked file to fail")
+	}
+	if err := ImportGit("missing.txt", "notes.fi", "", false); err == nil {
+		t.Error("expected import of a path without revisions to fail")
+	}
+}
+
+func TestImportRollback(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte("one\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := tr.StartTracking("notes.txt"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte(strings.Repeat("a long line\n", 200)), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	if _, _, err := cm.CommitUnit("notes.txt", "Grow"); err != nil {
+		t.Fatalf("failed to commit file: %v", err)
+	}
+	if err := ExportGit("notes.txt", "notes.fi", ""); err != nil {
+		t.Fatalf("ExportGit() failed: %v", err)
+	}
+	if err := os.WriteFile("copy.txt", []byte("local\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	objects, err := os.ReadDir(".qwe/_object")
+	if err != nil {
+		t.Fatalf("failed to list objects: %v", err)
+	}
+
+	// The base version is written, the large commit fails on an unknown codec
+	t.Cleanup(func() { cp.SetPolicy(cp.DefaultPolicy) })
+	cp.SetPolicy(cp.Policy{Codec: cp.Zlib, Large: cp.Codec(0x7f), LargeSize: 1024})
+	if err := ImportGit("copy.txt", "notes.fi", "notes.txt", true); err == nil {
+		t.Fatal("expected the import to fail")
+	}
+
+	content, err := os.ReadFile("copy.txt")
+	if err != nil || string(content) != "local\n" {
+		t.Errorf("expected the working file to be kept, got %q, %v", content, err)
+	}
+	if after, err := os.ReadDir(".qwe/_object"); err != nil || len(after) != len(objects) {
+		t.Errorf("expected the written objects to be removed, got %d objects instead of %d", len(after), len(objects))
+	}
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	if _, ok := tracker[utl.Hasher("copy.txt")]; ok {
+		t.Error("expected copy.txt to stay untracked")
+	}
+}
@@ -0,0 +1,482 @@
+package gitbridge
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	bh "github.com/mainak55512/qwe/binaryhandler"
+	cm "github.com/mainak55512/qwe/commit"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// A version of the imported path found in a git fast-export stream
+type revision struct {
+	ref       string
+	content   []byte
+	message   string
+	author    string
+	timeStamp string
+}
+
+// Reads a git fast-export stream and collects the versions of every path, renames carry the history over
+type streamReader struct {
+	r         *bufio.Reader
+	blobs     map[string][]byte     // mark -> blob content
+	tree      map[string]string     // path -> mark or inline key of the content
+	inline    map[string][]byte     // inline key -> content
+	histories map[string][]revision // path -> revisions that changed it
+}
+
+// Returns the next line without the trailing line feed, io.EOF at the end of the stream
+func (s *streamReader) readLine() (string, error) {
+	line, err := s.r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+// Reads the payload of a 'data <count>' command
+func (s *streamReader) readData(line string) ([]byte, error) {
+	count, err := strconv.Atoi(strings.TrimPrefix(line, "data "))
+	if !strings.HasPrefix(line, "data ") || err != nil || count < 0 {
+		return nil, fmt.Errorf("%w: expected data, got %q", er.GitStreamErr, line)
+	}
+	content := make([]byte, count)
+	if _, err = io.ReadFull(s.r, content); err != nil {
+		return nil, fmt.Errorf("%w: %v", er.GitStreamErr, err)
+	}
+	// The payload can be followed by an optional line feed
+	if next, err := s.r.Peek(1); err == nil && next[0] == '\n' {
+		s.r.ReadByte()
+	}
+	return content, nil
+}
+
+// Converts a path of the stream, quoted paths use C style escapes
+func unquotePath(path string) string {
+	if strings.HasPrefix(path, "\"") {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			return unquoted
+		}
+	}
+	return path
+}
+
+// Splits the source and destination path of a copy or rename command
+func splitPaths(args string) (string, string) {
+	if strings.HasPrefix(args, "\"") {
+		for i := 1; i < len(args); i++ {
+			if args[i] == '\\' {
+				i++
+			} else if args[i] == '"' {
+				return unquotePath(args[:i+1]), unquotePath(strings.TrimPrefix(args[i+1:], " "))
+			}
+		}
+	}
+	src, dest, _ := strings.Cut(args, " ")
+	return src, unquotePath(dest)
+}
+
+// Converts an 'author' or 'committer' line to the author name and the tracker time stamp
+func parseSignature(signature string) (string, string) {
+	name := signature
+	timeStamp := ""
+	if open := strings.LastIndex(signature, "<"); open >= 0 {
+		name = strings.TrimSpace(signature[:open])
+		if close := strings.LastIndex(signature, ">"); close > open {
+			fields := strings.Fields(signature[close+1:])
+			if len(fields) > 0 {
+				if unix, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+					timeStamp = time.Unix(unix, 0).In(time.Local).Format(cm.TimeStampLayout)
+				}
+			}
+		}
+	}
+	return name, timeStamp
+}
+
+func (s *streamReader) read() error {
+	line, err := s.readLine()
+	for err == nil {
+		switch {
+		case line == "blob":
+			line, err = s.readBlob()
+			continue
+		case strings.HasPrefix(line, "commit "):
+			line, err = s.readCommit()
+			continue
+		case strings.HasPrefix(line, "tag "):
+			line, err = s.skipTag()
+			continue
+		case line == "", line == "done",
+			strings.HasPrefix(line, "reset "), strings.HasPrefix(line, "from "),
+			strings.HasPrefix(line, "feature "), strings.HasPrefix(line, "option "),
+			strings.HasPrefix(line, "progress "):
+		default:
+			return fmt.Errorf("%w: unexpected command %q", er.GitStreamErr, line)
+		}
+		line, err = s.readLine()
+	}
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// Reads a blob and returns the line following it
+func (s *streamReader) readBlob() (string, error) {
+	mark := ""
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case strings.HasPrefix(line, "mark "):
+			mark = strings.TrimPrefix(line, "mark ")
+		case strings.HasPrefix(line, "original-oid "):
+		default:
+			content, err := s.readData(line)
+			if err != nil {
+				return "", err
+			}
+			if mark != "" {
+				s.blobs[mark] = content
+			}
+			return s.readLine()
+		}
+	}
+}
+
+// Reads an annotated tag and returns the line following it
+func (s *streamReader) skipTag() (string, error) {
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(line, "data ") {
+			if _, err = s.readData(line); err != nil {
+				return "", err
+			}
+			return s.readLine()
+		}
+	}
+}
+
+// Reads a commit, records a revision if it changed the imported path and returns the line following it
+func (s *streamReader) readCommit() (string, error) {
+	var rev revision
+	var committer, committerTime string
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(line, "data ") {
+			message, err := s.readData(line)
+			if err != nil {
+				return "", err
+			}
+			rev.message = strings.TrimRight(string(message), "\n")
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "author "):
+			rev.author, rev.timeStamp = parseSignature(strings.TrimPrefix(line, "author "))
+		case strings.HasPrefix(line, "committer "):
+			committer, committerTime = parseSignature(strings.TrimPrefix(line, "committer "))
+		}
+	}
+	if rev.author == "" {
+		rev.author, rev.timeStamp = committer, committerTime
+	}
+
+	touched := make(map[string]struct{})
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				s.record(rev, touched)
+			}
+			return "", err
+		}
+		switch {
+		case strings.HasPrefix(line, "from "), strings.HasPrefix(line, "merge "), strings.HasPrefix(line, "N "):
+		case line == "deleteall":
+			s.tree = make(map[string]string)
+		case strings.HasPrefix(line, "M "):
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) != 4 {
+				return "", fmt.Errorf("%w: invalid file command %q", er.GitStreamErr, line)
+			}
+			path := unquotePath(fields[3])
+			ref := fields[2]
+			if ref == "inline" {
+				next, err := s.readLine()
+				if err != nil {
+					return "", err
+				}
+				content, err := s.readData(next)
+				if err != nil {
+					return "", err
+				}
+				ref = fmt.Sprintf("inline:%d", len(s.inline))
+				s.inline[ref] = content
+			}
+			s.tree[path] = ref
+			touched[path] = struct{}{}
+		case strings.HasPrefix(line, "D "):
+			delete(s.tree, unquotePath(strings.TrimPrefix(line, "D ")))
+		case strings.HasPrefix(line, "C "), strings.HasPrefix(line, "R "):
+			src, dest := splitPaths(line[2:])
+			if ref, ok := s.tree[src]; ok {
+				s.tree[dest] = ref
+				s.histories[dest] = append([]revision(nil), s.histories[src]...)
+				touched[dest] = struct{}{}
+			}
+			if line[0] == 'R' {
+				delete(s.tree, src)
+				delete(s.histories, src)
+			}
+		default:
+			s.record(rev, touched)
+			return line, nil
+		}
+	}
+}
+
+// Records the commit as a revision of every touched path whose content changed
+func (s *streamReader) record(rev revision, touched map[string]struct{}) {
+	for path := range touched {
+		ref, ok := s.tree[path]
+		history := s.histories[path]
+		if !ok || (len(history) > 0 && history[len(history)-1].ref == ref) {
+			continue
+		}
+		rev.ref = ref
+		s.histories[path] = append(history, rev)
+	}
+}
+
+// Returns the revisions of a path with their content
+func (s *streamReader) revisions(path string) []revision {
+	revisions := s.histories[path]
+	for i := range revisions {
+		if content, ok := s.inline[revisions[i].ref]; ok {
+			revisions[i].content = content
+		} else {
+			revisions[i].content = s.blobs[revisions[i].ref]
+		}
+	}
+	return revisions
+}
+
+// Replays the history of a path from a git fast-export stream as qwe commits of filePath.
+// gitPath is the path inside the git repository, filePath is used if it is empty.
+// The first revision becomes the base version, every later revision a commit with its original message, author and time stamp.
+func ImportGit(filePath, input, gitPathName string, force bool) (err error) {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return err
+	}
+	if _, ok := tracker[utl.Hasher(filePath)]; ok {
+		return er.FileTracked
+	}
+
+	var in io.Reader = os.Stdin
+	if input != "" && input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	if gitPathName == "" {
+		gitPathName = filePath
+	}
+
+	s := &streamReader{
+		r:         bufio.NewReader(in),
+		blobs:     make(map[string][]byte),
+		tree:      make(map[string]string),
+		inline:    make(map[string][]byte),
+		histories: make(map[string][]revision),
+	}
+	if err = s.read(); err != nil {
+		return err
+	}
+	revisions := s.revisions(treePath(gitPathName))
+	if len(revisions) == 0 {
+		return fmt.Errorf("%w: %s", er.NoGitRevisions, treePath(gitPathName))
+	}
+
+	// The working file ends up with the latest revision, refuse to silently replace anything else
+	latest := revisions[len(revisions)-1].content
+	if utl.FileExists(filePath) && !force {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(content, latest) {
+			return fmt.Errorf("%w: %s", er.ImportOverwrite, filePath)
+		}
+	}
+	// Objects are written first and the tracker once, if anything fails the objects are removed and the working file is restored
+	var original []byte
+	existed := utl.FileExists(filePath)
+	if existed {
+		if original, err = os.ReadFile(filePath); err != nil {
+			return err
+		}
+	}
+	previous, err := json.MarshalIndent(tracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		return err
+	}
+	previousGroup, err := json.MarshalIndent(groupTracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+	var staged []string
+	written, trackerSaved := false, false
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, objID := range staged {
+			os.Remove(".qwe/_object/" + objID)
+		}
+		if trackerSaved {
+			if restoreErr := tr.RestoreTrackers(previous, previousGroup); restoreErr != nil {
+				fmt.Printf("Warning: failed to restore the trackers: %v\n", restoreErr)
+			}
+		}
+		if !written {
+			return
+		}
+		if existed {
+			err = errors.Join(err, os.WriteFile(filePath, original, 0644))
+		} else {
+			os.Remove(filePath)
+		}
+	}()
+
+	val, err := importObjects(filePath, revisions, &staged)
+	if err != nil {
+		return err
+	}
+	tracker[utl.Hasher(filePath)] = val
+	marshalContent, err := json.MarshalIndent(tracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+
+	if dir := filepath.Dir(filePath); dir != "." {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	written = true
+	if err = os.WriteFile(filePath, latest, 0644); err != nil {
+		return err
+	}
+	if err = tr.SaveTracker(tr.FileTrackerType, marshalContent); err != nil {
+		return err
+	}
+	trackerSaved = true
+	if err := tr.UpdateTrackedFile(utl.Hasher(filePath), filePath); err != nil {
+		fmt.Printf("Warning: failed to update tracked files: %v\n", err)
+	}
+	if err = tr.SyncSession(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d revisions of %s\n", len(val.Versions)+1, filePath)
+	return nil
+}
+
+// Writes the base version and the commits of the revisions and returns the tracker entry of filePath,
+// the ids of the written objects are added to staged. Revisions without a change of the content are skipped.
+func importObjects(filePath string, revisions []revision, staged *[]string) (tr.Tracker, error) {
+	binary := bh.IsBinary(revisions[0].content)
+	var base string
+	var err error
+	if binary {
+		base, err = bh.WriteObject(filePath, "", nil, revisions[0].content)
+	} else {
+		base, err = cm.WriteObject("_base_", filePath, revisions[0].content)
+	}
+	if err != nil {
+		return tr.Tracker{}, err
+	}
+	*staged = append(*staged, base)
+	val := tr.Tracker{Base: base, Current: base, Versions: []tr.VersionDetails{}}
+
+	prev := revisions[0].content
+	for _, rev := range revisions[1:] {
+		var objID string
+		if binary {
+			if bytes.Equal(prev, rev.content) {
+				continue
+			}
+			objID, err = bh.WriteObject(filePath, val.Current, prev, rev.content)
+		} else {
+			var stat [3]int
+			if stat, err = cm.LineStat(prev, rev.content); err != nil {
+				return tr.Tracker{}, err
+			}
+			if stat == [3]int{} {
+				continue // e.g. only the line endings changed
+			}
+			var delta string
+			if delta, err = cm.TextDelta(prev, rev.content); err != nil {
+				return tr.Tracker{}, err
+			}
+			objID, err = cm.WriteObject("", filePath, []byte(delta))
+		}
+		if err != nil {
+			return tr.Tracker{}, err
+		}
+		*staged = append(*staged, objID)
+
+		// Commits keep their original details
+		version := tr.VersionDetails{
+			UID:           objID,
+			CommitMessage: rev.message,
+			TimeStamp:     rev.timeStamp,
+			Author:        rev.author,
+		}
+		if version.TimeStamp == "" {
+			version.TimeStamp = time.Now().String()[:16]
+		}
+		if version.Author == "" {
+			version.Author = utl.Author()
+		}
+		val.Versions = append(val.Versions, version)
+		val.Current = objID
+		prev = rev.content
+	}
+	return val, nil
+}
@@ -1,3 +1,5 @@
 module github.com/mainak55512/qwe
 
 go 1.25.0
+
+require github.com/klauspost/compress v1.18.0
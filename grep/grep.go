@@ -0,0 +1,167 @@
+package grep
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	tw "text/tabwriter"
+
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	res "github.com/mainak55512/qwe/reconstruct"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// A tracked text file to be searched
+type searchTarget struct {
+	fileName string
+	val      tr.Tracker
+}
+
+// Returns the tracked text files to search, a single file if filePath is supplied
+func targets(filePath string) ([]searchTarget, error) {
+	if !utl.QweIsInWorkingDir() {
+		return nil, er.RepoNotFound
+	}
+
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []searchTarget
+	if filePath != "" {
+		val, ok := tracker[utl.Hasher(filePath)]
+		if !ok {
+			return nil, er.FileNotTracked
+		}
+		if strings.HasPrefix(val.Base, "_bin_") {
+			return nil, er.BinFileErr
+		}
+		return append(result, searchTarget{fileName: filePath, val: val}), nil
+	}
+
+	trackedFiles, err := tr.LoadTrackedFilesFromFile(filepath.Join(tr.QweDir, tr.FileName))
+	if err != nil {
+		return nil, err
+	}
+	for fileID, val := range tracker {
+		f, ok := trackedFiles[fileID]
+		if !ok || strings.HasPrefix(val.Base, "_bin_") {
+			continue
+		}
+		result = append(result, searchTarget{fileName: f.FilePath, val: val})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].fileName < result[j].fileName
+	})
+	return result, nil
+}
+
+// Converts a commit id to the form shown to the user
+func commitName(commitID int) string {
+	if commitID == res.BaseVersion {
+		return "base"
+	}
+	return fmt.Sprintf("%d", commitID)
+}
+
+// Reconstructs every version of the file and calls fn with the content of each version
+func eachVersion(t searchTarget, fn func(commitID int, content []byte) error) error {
+	target := ".qwe/_object/_grep_" + utl.Hasher(fmt.Sprintf("%s%d", t.fileName, time.Now().UnixNano()))
+	defer os.Remove(target)
+
+	return res.EachVersion(t.val, target, func(commitID int) error {
+		content, err := os.ReadFile(target)
+		if err != nil {
+			return err
+		}
+		return fn(commitID, content)
+	})
+}
+
+// Prints the lines matching the pattern in every committed version of the tracked text files
+func Grep(pattern, filePath string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		re = regexp.MustCompile(regexp.QuoteMeta(pattern))
+	}
+
+	files, err := targets(filePath)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, t := range files {
+		err := eachVersion(t, func(commitID int, content []byte) error {
+			scanner := bufio.NewScanner(bytes.NewReader(content))
+			line := 0
+			for scanner.Scan() {
+				line++
+				if re.MatchString(scanner.Text()) {
+					found = true
+					fmt.Printf("%s@%s:%d: %s\n", t.fileName, commitName(commitID), line, scanner.Text())
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !found {
+		fmt.Println("No match found!")
+	}
+	return nil
+}
+
+// Prints the commits in which the number of occurrences of the text changed
+func Pickaxe(text, filePath string) error {
+	if text == "" {
+		return er.CLIGrepErr
+	}
+
+	files, err := targets(filePath)
+	if err != nil {
+		return err
+	}
+
+	w := new(tw.Writer)
+	w.Init(os.Stdout, 0, 0, 0, ' ', tw.TabIndent)
+
+	found := false
+	for _, t := range files {
+		previous := 0
+		err := eachVersion(t, func(commitID int, content []byte) error {
+			count := strings.Count(string(content), text)
+			if count != previous {
+				found = true
+				message, timeStamp := "Base version", ""
+				if commitID != res.BaseVersion {
+					message = t.val.Versions[commitID].CommitMessage
+					timeStamp = t.val.Versions[commitID].TimeStamp
+				}
+				fmt.Fprintf(w, "\nFile:\t%s\nID:\t%s\nOccurrences:\t%d -> %d\nCommit Message:\t%s\nTime Stamp:\t%s\n",
+					t.fileName, commitName(commitID), previous, count, message, timeStamp)
+			}
+			previous = count
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if !found {
+		fmt.Println("No commit changed the occurrences of", text)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package grep
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	cm "github.com/mainak55512/qwe/commit"
+	in "github.com/mainak55512/qwe/initializer"
+	er "github.com/mainak55512/qwe/qwerror"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// Returns what fn prints to stdout
+func captureOutput(t *testing.T, fn func() error) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	fnErr := fn()
+	os.Stdout = stdout
+	w.Close()
+	var out bytes.Buffer
+	io.Copy(&out, r)
+	if fnErr != nil {
+		t.Fatalf("command failed: %v", fnErr)
+	}
+	return out.String()
+}
+
+func setupHistory(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	versions := map[string][]string{
+		"notes.txt": {"alpha\nbeta\n", "alpha\nTODO fix beta\n", "alpha\nTODO fix beta\nTODO gamma\n", "alpha\ngamma\n"},
+		"other.txt": {"nothing here\n", "TODO other\n"},
+	}
+	for filePath, contents := range versions {
+		if err := os.WriteFile(filePath, []byte(contents[0]), 0o644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+		if _, err := tr.StartTracking(filePath); err != nil {
+			t.Fatalf("failed to track file: %v", err)
+		}
+		for i, content := range contents[1:] {
+			if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			if _, _, err := cm.CommitUnit(filePath, "commit "+string(rune('a'+i))); err != nil {
+				t.Fatalf("failed to commit file: %v", err)
+			}
+		}
+	}
+	if err := os.WriteFile("image.bin", []byte("\x00\x01TODO"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := tr.StartTracking("image.bin"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+}
+
+func TestGrep(t *testing.T) {
+	setupHistory(t)
+
+	out := captureOutput(t, func() error { return Grep("TODO (fix|gamma)", "") })
+	want := "notes.txt@0:2: TODO fix beta\n" +
+		"notes.txt@1:2: TODO fix beta\n" +
+		"notes.txt@1:3: TODO gamma\n"
+	if out != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, out)
+	}
+
+	// Invalid patterns are searched as plain text, binary files are skipped
+	out = captureOutput(t, func() error { return Grep("TODO other(", "") })
+	if out != "No match found!\n" {
+		t.Errorf("expected no match for the literal pattern, got %q", out)
+	}
+	out = captureOutput(t, func() error { return Grep("^TODO", "other.txt") })
+	if out != "other.txt@0:1: TODO other\n" {
+		t.Errorf("expected the match of other.txt only, got %q", out)
+	}
+
+	if err := Grep("TODO", "image.bin"); !errors.Is(err, er.BinFileErr) {
+		t.Errorf("expected BinFileErr for a binary file, got %v", err)
+	}
+	if err := Grep("TODO", "missing.txt"); !errors.Is(err, er.FileNotTracked) {
+		t.Errorf("expected FileNotTracked, got %v", err)
+	}
+}
+
+func TestPickaxe(t *testing.T) {
+	setupHistory(t)
+
+	out := captureOutput(t, func() error { return Pickaxe("TODO", "notes.txt") })
+	blocks := strings.Split(strings.TrimSpace(out), "\n\n")
+	for i := range blocks {
+		blocks[i] = strings.Join(strings.Fields(blocks[i]), " ") // tabwriter padding
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("expected the three commits changing the occurrences, got\n%s", out)
+	}
+	for i, want := range []string{"0 -> 1", "1 -> 2", "2 -> 0"} {
+		if !strings.Contains(blocks[i], "Occurrences: "+want) {
+			t.Errorf("block %d: expected occurrences %s, got\n%s", i, want, blocks[i])
+		}
+	}
+	if !strings.Contains(blocks[0], "ID: 0 ") || !strings.Contains(blocks[2], "commit c") {
+		t.Errorf("expected commit ids and messages in the report, got\n%s", out)
+	}
+
+	// Every text file is searched without a file path
+	out = captureOutput(t, func() error { return Pickaxe("TODO other", "") })
+	if !strings.Contains(out, "other.txt") || strings.Contains(out, "notes.txt") {
+		t.Errorf("expected only other.txt, got\n%s", out)
+	}
+	out = captureOutput(t, func() error { return Pickaxe("absent", "") })
+	if out != "No commit changed the occurrences of absent\n" {
+		t.Errorf("expected no commit, got %q", out)
+	}
+	if err := Pickaxe("", ""); !errors.Is(err, er.CLIGrepErr) {
+		t.Errorf("expected CLIGrepErr for empty text, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// Replaces the versions from..to of a group with a single version that records the files of version to.
+// The checked out version may only be version to, the file commits are not changed.
+func SquashGroup(groupName string, from, to int, message string) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		return err
+	}
+	groupID := utl.Hasher(groupName)
+	gr, ok := groupTracker[groupID]
+	if !ok {
+		return er.InvalidGroup
+	}
+	if from < 0 || to > len(gr.VersionOrder)-1 || from >= to {
+		return er.InvalidCommitNo
+	}
+
+	if slices.Contains(gr.VersionOrder[from:to], gr.Current) {
+		return er.CheckedOutRemoved
+	}
+
+	kept := gr.VersionOrder[to]
+	for _, versionID := range gr.VersionOrder[from:to] {
+		delete(gr.Versions, versionID)
+	}
+	version := gr.Versions[kept]
+	version.CommitMessage = message
+	gr.Versions[kept] = version
+	gr.VersionOrder = append(append([]string{}, gr.VersionOrder[:from]...), gr.VersionOrder[to:]...)
+	groupTracker[groupID] = gr
+
+	content, err := json.MarshalIndent(groupTracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+	if err = tr.SaveTracker(tr.GroupTrackerType, content); err != nil {
+		return err
+	}
+	fmt.Printf("Squashed versions %d to %d of group %s into version %d\n", from, to, groupName, from)
+	return nil
+}
@@ -0,0 +1,313 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	bh "github.com/mainak55512/qwe/binaryhandler"
+	cm "github.com/mainak55512/qwe/commit"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	res "github.com/mainak55512/qwe/reconstruct"
+	st "github.com/mainak55512/qwe/stash"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// A file history being rewritten together with the groups that refer to its commits
+type rewrite struct {
+	filePath     string
+	fileID       string
+	tracker      tr.TrackerSchema
+	groupTracker tr.GroupTrackerSchema
+	val          tr.Tracker
+}
+
+func load(filePath string) (*rewrite, error) {
+	if !utl.QweIsInWorkingDir() {
+		return nil, er.RepoNotFound
+	}
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return nil, err
+	}
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		return nil, err
+	}
+	fileID := utl.Hasher(filePath)
+	val, ok := tracker[fileID]
+	if !ok {
+		return nil, er.FileNotTracked
+	}
+	return &rewrite{
+		filePath:     filePath,
+		fileID:       fileID,
+		tracker:      tracker,
+		groupTracker: groupTracker,
+		val:          val,
+	}, nil
+}
+
+// Returns the object id of a commit of the rewritten history, BaseVersion returns the base object
+func objectOf(val tr.Tracker, commitID int) string {
+	if commitID == res.BaseVersion {
+		return val.Base
+	}
+	return val.Versions[commitID].UID
+}
+
+// Replaces the history of the file. remap converts an old commit id to the id of the commit in the new history
+// that holds the same or a later state, it is used for the checked out version and for group references.
+func (r *rewrite) save(newVal tr.Tracker, remap func(commitID int) int) error {
+	current, _ := r.val.CommitID(r.val.Current)
+	newVal.Current = objectOf(newVal, remap(current))
+	r.tracker[r.fileID] = newVal
+
+	for groupID, gr := range r.groupTracker {
+		for versionID, version := range gr.Versions {
+			f, ok := version.Files[r.fileID]
+			if !ok {
+				continue
+			}
+			commitID, ok := r.val.CommitID(f.UID)
+			if !ok {
+				continue
+			}
+			f.UID = objectOf(newVal, remap(commitID))
+			version.Files[r.fileID] = f
+			gr.Versions[versionID] = version
+		}
+		r.groupTracker[groupID] = gr
+	}
+
+	content, err := json.MarshalIndent(r.tracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+	if err = tr.SaveTracker(tr.FileTrackerType, content); err != nil {
+		return err
+	}
+	content, err = json.MarshalIndent(r.groupTracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+	if err = tr.SaveTracker(tr.GroupTrackerType, content); err != nil {
+		return err
+	}
+
+	// Objects are only removed once nothing on disk refers to them anymore
+	if err = tr.SyncSession(); err != nil {
+		return err
+	}
+	if err = r.removeUnreferenced(); err != nil {
+		fmt.Printf("Warning: failed to remove unreferenced objects: %v\n", err)
+	}
+	return nil
+}
+
+// Deletes the objects of the previous history of the file that no file commit, group version, stash or reflog
+// snapshot refers to. Objects that deltas of referenced objects are based on are kept. Objects kept for the
+// reflog are deleted once their entries expire.
+func (r *rewrite) removeUnreferenced() error {
+	var referenced []string
+	for _, val := range r.tracker {
+		referenced = append(referenced, val.Base, val.Current)
+		for _, version := range val.Versions {
+			referenced = append(referenced, version.UID)
+		}
+	}
+	for _, gr := range r.groupTracker {
+		for _, version := range gr.Versions {
+			for _, f := range version.Files {
+				referenced = append(referenced, f.UID)
+			}
+		}
+	}
+	stashed, err := st.Objects()
+	if err != nil {
+		return err
+	}
+	referenced = append(referenced, stashed...)
+	snapshots, err := tr.SnapshotObjects()
+	if err != nil {
+		return err
+	}
+	referenced = append(referenced, snapshots...)
+	kept, err := bh.WithDependencies(".qwe/_object", referenced)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool, len(kept))
+	for _, objID := range kept {
+		keep[objID] = true
+	}
+
+	candidates := []string{r.val.Base, r.val.Current}
+	for _, version := range r.val.Versions {
+		candidates = append(candidates, version.UID)
+	}
+	for _, objID := range candidates {
+		if objID == "" || keep[objID] {
+			continue
+		}
+		if err := os.Remove(".qwe/_object/" + objID); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replaces the commits from..to of a file with a single commit that reproduces the state of commit to.
+// Later commits keep their content, group versions referring to a replaced commit refer to the new commit.
+// Objects of the replaced commits are deleted once nothing else, including the reflog, refers to them.
+func Squash(filePath string, from, to int, message string) error {
+	r, err := load(filePath)
+	if err != nil {
+		return err
+	}
+	val := r.val
+	if from < 0 || to > len(val.Versions)-1 || from >= to {
+		return er.InvalidCommitNo
+	}
+	// No commit of the new history holds the state of a commit before to
+	if current, _ := val.CommitID(val.Current); current >= from && current < to {
+		return er.CheckedOutRemoved
+	}
+
+	last := val.Versions[to]
+	squashed := tr.VersionDetails{
+		UID:           last.UID, // a binary object rebuilds its whole version, the last one already holds the end state
+		CommitMessage: message,
+		TimeStamp:     last.TimeStamp,
+		Author:        last.Author,
+	}
+	if !strings.HasPrefix(val.Base, "_bin_") {
+		prevID := from - 1
+		if from == 0 {
+			prevID = res.BaseVersion
+		}
+		prev, err := res.Content(val, prevID)
+		if err != nil {
+			return err
+		}
+		next, err := res.Content(val, to)
+		if err != nil {
+			return err
+		}
+		delta, err := cm.TextDelta(prev, next)
+		if err != nil {
+			return err
+		}
+		if squashed.UID, err = cm.WriteObject("", filePath, []byte(delta)); err != nil {
+			return err
+		}
+	}
+
+	newVal := val
+	newVal.Versions = append(append(append([]tr.VersionDetails{}, val.Versions[:from]...), squashed), val.Versions[to+1:]...)
+
+	removed := to - from
+	err = r.save(newVal, func(commitID int) int {
+		switch {
+		case commitID < from:
+			return commitID
+		case commitID <= to:
+			return from
+		}
+		return commitID - removed
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Squashed commits %d to %d of %s into commit %d\n", from, to, filePath, from)
+	return nil
+}
+
+// Returns the commit messages of the commits from..to joined into one message
+func SquashMessage(filePath string, from, to int) (string, error) {
+	r, err := load(filePath)
+	if err != nil {
+		return "", err
+	}
+	if from < 0 || to > len(r.val.Versions)-1 || from >= to {
+		return "", er.InvalidCommitNo
+	}
+	var messages []string
+	for _, version := range r.val.Versions[from : to+1] {
+		messages = append(messages, version.CommitMessage)
+	}
+	return strings.Join(messages, "; "), nil
+}
+
+// Folds all but the latest keep commits of a file into its base version
+func Prune(filePath string, keep int) error {
+	r, err := load(filePath)
+	if err != nil {
+		return err
+	}
+	if keep < 0 {
+		return er.InvalidCommitNo
+	}
+	return r.prune(max(len(r.val.Versions)-keep, 0))
+}
+
+// Folds the commits of a file that are older than before into its base version
+func PruneBefore(filePath string, before time.Time) error {
+	r, err := load(filePath)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, version := range r.val.Versions {
+		t, err := time.ParseInLocation(cm.TimeStampLayout, version.TimeStamp, time.Local)
+		if err != nil || !t.Before(before) {
+			break
+		}
+		count++
+	}
+	return r.prune(count)
+}
+
+// Replaces the base version with the state of commit count-1 and drops the first count commits.
+// The remaining commits keep their deltas as they apply to the state of the previous commit.
+func (r *rewrite) prune(count int) error {
+	if count == 0 {
+		fmt.Println("No commits of", r.filePath, "to prune")
+		return nil
+	}
+	val := r.val
+	// The new base holds the state of commit count-1, older states are gone
+	if current, _ := val.CommitID(val.Current); current < count-1 {
+		return er.CheckedOutRemoved
+	}
+
+	newVal := val
+	newVal.Versions = append([]tr.VersionDetails{}, val.Versions[count:]...)
+	if strings.HasPrefix(val.Base, "_bin_") {
+		newVal.Base = val.Versions[count-1].UID // a binary object rebuilds its whole version, deltas keep their bases
+	} else {
+		content, err := res.Content(val, count-1)
+		if err != nil {
+			return err
+		}
+		if newVal.Base, err = cm.WriteObject("_base_", r.filePath, content); err != nil {
+			return err
+		}
+	}
+
+	err := r.save(newVal, func(commitID int) int {
+		if commitID < count {
+			return res.BaseVersion
+		}
+		return commitID - count
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Pruned %d commits of %s into the base version\n", count, r.filePath)
+	return nil
+}
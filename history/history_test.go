@@ -0,0 +1,269 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	bh "github.com/mainak55512/qwe/binaryhandler"
+	cm "github.com/mainak55512/qwe/commit"
+	in "github.com/mainak55512/qwe/initializer"
+	er "github.com/mainak55512/qwe/qwerror"
+	qt "github.com/mainak55512/qwe/qwetest"
+	utl "github.com/mainak55512/qwe/qweutils"
+	res "github.com/mainak55512/qwe/reconstruct"
+	rv "github.com/mainak55512/qwe/revert"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+func trackers(t *testing.T, filePath string) (tr.Tracker, tr.GroupTracker) {
+	t.Helper()
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read group tracker: %v", err)
+	}
+	return tracker[utl.Hasher(filePath)], groupTracker[utl.Hasher("docs")]
+}
+
+func checkContents(t *testing.T, val tr.Tracker, want map[int]string) {
+	t.Helper()
+	if len(val.Versions) != len(want)-1 {
+		t.Fatalf("expected %d commits, got %+v", len(want)-1, val.Versions)
+	}
+	for commitID, content := range want {
+		got, err := res.Content(val, commitID)
+		if err != nil {
+			t.Fatalf("failed to reconstruct commit %d: %v", commitID, err)
+		}
+		if string(got) != content {
+			t.Errorf("commit %d: expected %q, got %q", commitID, content, got)
+		}
+	}
+}
+
+// Returns the commit id each group version refers to, in commit order of the group
+func groupRefs(t *testing.T, val tr.Tracker, gr tr.GroupTracker, fileID string) []int {
+	t.Helper()
+	var refs []int
+	for _, versionID := range gr.VersionOrder {
+		commitID, ok := val.CommitID(gr.Versions[versionID].Files[fileID].UID)
+		if !ok {
+			t.Fatalf("group version %s refers to a commit that is not part of the history", versionID)
+		}
+		refs = append(refs, commitID)
+	}
+	return refs
+}
+
+func TestSquashAndPrune(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte("a\nb\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	if err := tr.StartGroupTracking("docs", []string{"notes.txt"}); err != nil {
+		t.Fatalf("failed to track file in group: %v", err)
+	}
+	qt.CommitFile(t, "notes.txt", "a\nb\nc\n", "Add c")
+	qt.CommitFile(t, "notes.txt", "a\nB\nc\n", "Change b")
+	if err := cm.CommitGroup("docs", "Group at commit 1", false, false); err != nil {
+		t.Fatalf("failed to commit group: %v", err)
+	}
+	qt.CommitFile(t, "notes.txt", "a\nB\n", "Remove c")
+	qt.CommitFile(t, "notes.txt", "A\nB\nd\n", "Change a, add d")
+	if err := cm.CommitGroup("docs", "Group at commit 3", false, false); err != nil {
+		t.Fatalf("failed to commit group: %v", err)
+	}
+	qt.CommitFile(t, "notes.txt", "A\nB\nd\ne\n", "Add e")
+
+	fileID := utl.Hasher("notes.txt")
+	if err := Squash("notes.txt", 3, 1, "Squashed"); err == nil {
+		t.Error("expected error for an invalid range")
+	}
+
+	before, _ := trackers(t, "notes.txt")
+	if err := Squash("notes.txt", 1, 3, "Squashed"); err != nil {
+		t.Fatalf("Squash() failed: %v", err)
+	}
+	val, gr := trackers(t, "notes.txt")
+	for _, version := range before.Versions[1:4] {
+		if utl.FileExists(".qwe/_object/" + version.UID) {
+			t.Errorf("expected the object of the squashed commit %q to be removed", version.CommitMessage)
+		}
+	}
+	checkContents(t, val, map[int]string{
+		res.BaseVersion: "a\nb\n",
+		0:               "a\nb\nc\n",
+		1:               "A\nB\nd\n",
+		2:               "A\nB\nd\ne\n",
+	})
+	if current, _ := val.CommitID(val.Current); val.Versions[1].CommitMessage != "Squashed" || current != 2 {
+		t.Errorf("expected the squashed commit and commit 2 checked out, got %+v", val)
+	}
+	refs := groupRefs(t, val, gr, fileID)
+	if len(refs) != 3 || refs[0] != res.BaseVersion || refs[1] != 1 || refs[2] != 1 {
+		t.Errorf("expected group references base, 1, 1, got %v", refs)
+	}
+
+	before = val
+	if err := Prune("notes.txt", 1); err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	val, gr = trackers(t, "notes.txt")
+	for _, objID := range []string{before.Base, before.Versions[0].UID, before.Versions[1].UID} {
+		if utl.FileExists(".qwe/_object/" + objID) {
+			t.Errorf("expected the pruned object %s to be removed", objID)
+		}
+	}
+	checkContents(t, val, map[int]string{
+		res.BaseVersion: "A\nB\nd\n",
+		0:               "A\nB\nd\ne\n",
+	})
+	for i, ref := range groupRefs(t, val, gr, fileID) {
+		if ref != res.BaseVersion {
+			t.Errorf("expected group version %d to refer to the new base, got %d", i, ref)
+		}
+	}
+	if current, _ := val.CommitID(val.Current); current != 0 {
+		t.Errorf("expected commit 0 to be checked out, got %d", current)
+	}
+}
+
+func TestPruneBinary(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile("logo.bin", []byte("\x00\x01one"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := tr.StartTracking("logo.bin"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	qt.CommitFile(t, "logo.bin", "\x00\x01two", "Two")
+	qt.CommitFile(t, "logo.bin", "\x00\x01three", "Three")
+
+	if err := Prune("logo.bin", 5); err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	if err := Prune("logo.bin", 1); err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	val, _ := trackers(t, "logo.bin")
+	checkContents(t, val, map[int]string{
+		res.BaseVersion: "\x00\x01two",
+		0:               "\x00\x01three",
+	})
+}
+
+func TestPruneKeepsDeltaBases(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	content := []byte("\x00\x01")
+	for i := range 4096 {
+		content = append(content, byte(i*7))
+	}
+	version := func(i int) string {
+		c := append([]byte{}, content...)
+		c[1000] = byte(i)
+		return string(c)
+	}
+	if err := os.WriteFile("logo.bin", []byte(version(0)), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := tr.StartTracking("logo.bin"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		qt.CommitFile(t, "logo.bin", version(i), "Change")
+	}
+	val, _ := trackers(t, "logo.bin")
+	if !bh.IsDelta(val.Versions[2].UID) {
+		t.Fatalf("expected commits to be stored as deltas, got %s", val.Versions[2].UID)
+	}
+
+	// The new base is a delta, the objects it is based on stay
+	if err := Prune("logo.bin", 1); err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	val, _ = trackers(t, "logo.bin")
+	checkContents(t, val, map[int]string{
+		res.BaseVersion: version(2),
+		0:               version(3),
+	})
+}
+
+func TestSquashUndoRevert(t *testing.T) {
+	t.Chdir(t.TempDir())
+	defer func(limit int) { tr.ReflogLimit = limit }(tr.ReflogLimit)
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte("a\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := tr.StartTracking("notes.txt"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	for _, content := range []string{"a\nb\n", "a\nb\nc\n", "a\nb\nc\nd\n"} {
+		tr.SetReflogCommand("commit")
+		qt.CommitFile(t, "notes.txt", content, "Change")
+	}
+	before, _ := trackers(t, "notes.txt")
+
+	// The reflog entry of the squash refers to the replaced commits, undo can restore them
+	tr.SetReflogCommand("squash")
+	if err := Squash("notes.txt", 0, 2, "Squashed"); err != nil {
+		t.Fatalf("Squash() failed: %v", err)
+	}
+	if err := tr.Undo(); err != nil {
+		t.Fatalf("Undo() failed: %v", err)
+	}
+	tr.SetReflogCommand("revert")
+	if err := rv.Revert(0, "notes.txt", false, false); err != nil {
+		t.Fatalf("Revert() failed: %v", err)
+	}
+	if content, _ := os.ReadFile("notes.txt"); string(content) != "a\nb\n" {
+		t.Errorf("expected the squashed commit 0 to be restored, got %q", content)
+	}
+
+	// A squash must not replace the checked out commit with a later state
+	tr.SetReflogCommand("squash")
+	if err := Squash("notes.txt", 0, 2, "Squashed"); !errors.Is(err, er.CheckedOutRemoved) {
+		t.Errorf("expected the checked out commit to block the squash, got %v", err)
+	}
+
+	// Objects only the reflog refers to are deleted once its entry expires
+	tr.SetReflogCommand("revert")
+	if err := rv.Revert(2, "notes.txt", false, false); err != nil {
+		t.Fatalf("Revert() failed: %v", err)
+	}
+	tr.ReflogLimit = 1
+	tr.SetReflogCommand("squash")
+	if err := Squash("notes.txt", 0, 2, "Squashed"); err != nil {
+		t.Fatalf("Squash() failed: %v", err)
+	}
+	for _, version := range before.Versions[:2] {
+		if !utl.FileExists(".qwe/_object/" + version.UID) {
+			t.Errorf("expected the object %s to be kept for undo", version.UID)
+		}
+	}
+	tr.SetReflogCommand("commit")
+	qt.CommitFile(t, "notes.txt", "a\nb\nc\nd\ne\n", "Change")
+	for _, version := range before.Versions[:2] {
+		if utl.FileExists(".qwe/_object/" + version.UID) {
+			t.Errorf("expected the object %s to be removed with the expired reflog entry", version.UID)
+		}
+	}
+}
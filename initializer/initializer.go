@@ -80,6 +80,8 @@ func GroupInit(groupName string) error {
 		Versions: map[string]tr.GroupVersionDetails{
 			groupObjectId: {
 				CommitMessage: "Initial Tracking",
+				TimeStamp:     time.Now().String()[:16],
+				Author:        utl.Author(),
 				Files:         map[string]tr.FileDetails{},
 			},
 		},
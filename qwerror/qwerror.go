@@ -47,7 +47,7 @@ var (
 	CLITrackErr        = new(26, "track command only accepts 'file path' as argument!")
 	CLIGrpTrackErr     = new(27, "group-track command accepts 'group name' and 'file path' as arguments!")
 	CLICommitErr       = new(28, "commit command accepts 'file path' and 'commit message' as arguments!")
-	CLIGrpCommitErr    = new(29, "group-commit command accepts 'group name' and 'commit message' as arguments!")
+	CLIGrpCommitErr    = new(29, "group-commit command accepts 'group name' and 'commit message' as arguments with optional --allow-empty and --dry-run!")
 	CLIListErr         = new(30, "list command only accepts 'file path' as argument!")
 	CLIGrpListErr      = new(31, "group-list command only accepts 'group name' as argument!")
 	CLIRevertErr       = new(32, "Revert command either accepts no argument or two mandatory arguments 'group name' and 'commit number'!")
@@ -62,4 +62,54 @@ var (
 	BinFileErr         = new(41, "Filetype is not supported yet!")
 	CLIShowFilesErr    = new(42, "tracked command doesn't take any argument!")
 	CLIUntrackErr      = new(43, "untrack command only accepts 'file path' as argument!")
+	CLIStashErr        = new(44, "stash command accepts 'file path' or 'group name' with an optional 'message', or one of list, pop [stash-id], drop [stash-id]!")
+	NothingToStash     = new(45, "No uncommitted changes to stash!")
+	InvalidStash       = new(46, "Invalid stash id!")
+	StashConflict      = new(47, "Can not apply stash over uncommitted changes!")
+	UncommittedChanges = new(48, "Uncommitted changes would be overwritten, use --stash to save them or --force to discard them!")
+	NothingToUndo      = new(49, "No command left to undo!")
+	CLIReflogErr       = new(50, "reflog command doesn't take any argument!")
+	CLIUndoErr         = new(51, "undo command doesn't take any argument!")
+	InvalidDate        = new(52, "Invalid date, use YYYY-MM-DD or 'YYYY-MM-DD HH:MM' format!")
+	CLILogErr          = new(53, "log command only accepts --path, --group, --since, --until, --grep, --author and --oneline flags!")
+	CLIGrepErr         = new(54, "grep command accepts 'pattern' or -S 'text' with an optional 'file path' as arguments!")
+	CLIBisectErr       = new(55, "bisect command accepts start <file path/group name> [bad] [good], good [commit number], bad [commit number], skip, reset or run <script> [arguments]!")
+	BisectNotStarted   = new(56, "No bisect in progress, use 'bisect start' first!")
+	BisectInProgress   = new(57, "A bisect is already in progress, use 'bisect reset' to end it!")
+	BisectBadRange     = new(58, "Bisect needs a good commit older than the bad commit!")
+	CLIExportGitErr    = new(59, "export-git command accepts 'file path' or 'group name' as argument with optional -o 'output file' and --branch 'branch name'!")
+	GitStreamErr       = new(60, "Invalid git fast-export stream!")
+	NoGitRevisions     = new(61, "No revision of the path found in the git stream!")
+	ImportOverwrite    = new(62, "File exists with different content than the latest git revision, use --force to overwrite it!")
+	CLIImportGitErr    = new(63, "import-git command accepts 'file path' as argument with optional -i 'stream file', --path 'path in git repository' and --force!")
+	CLIArchiveErr      = new(64, "archive command accepts 'group name', 'commit number' and -o 'output file' as arguments!")
+	ArchiveFormatErr   = new(65, "Unsupported archive format, output file must end with .tar.gz, .tgz, .tar or .zip!")
+	CLIBundleErr       = new(66, "bundle command accepts 'create' with 'bundle file' and optional 'file paths/group names', or 'unbundle' with 'bundle file' as arguments!")
+	InvalidBundle      = new(67, "Invalid or corrupted bundle!")
+	MissingObject      = new(68, "Object of a tracked file is missing!")
+	InvalidRemote      = new(69, "Remote not found or not a qwe repository!")
+	RemoteExists       = new(70, "Remote already exists!")
+	RemoteLocked       = new(71, "Remote is locked by another push or pull!")
+	RemoteNotEmpty     = new(72, "Directory for the remote store is not empty!")
+	CLIRemoteErr       = new(73, "remote command accepts 'add' with 'remote name', 'path' and optional --init, 'remove' with 'remote name' or 'list' as arguments!")
+	CLIPushErr         = new(74, "push command accepts 'remote name' and optional 'file paths/group names' as arguments!")
+	CLIPullErr         = new(75, "pull command accepts 'remote name' and optional 'file paths/group names' as arguments!")
+	InvalidRequest     = new(76, "Invalid request!")
+	ServerReadOnly     = new(77, "Server is read-only, start it with --writable to allow commits!")
+	CLIServeErr        = new(78, "serve command accepts optional --addr 'host:port' and --writable!")
+	CLIWatchErr        = new(79, "watch command accepts optional 'file paths/group names' with --interval, --debounce and --min-gap durations, or 'squash' with 'file path/group name'!")
+	CLISquashErr       = new(80, "squash command accepts 'file path', 'from commit number', 'to commit number' and optional 'commit message' as arguments!")
+	CLIPruneErr        = new(81, "prune command accepts 'file path' with either --keep 'number of commits' or --older-than 'date' as arguments!")
+	CLIGroupDiffErr    = new(82, "group-diff command accepts 'group name' and optionally one or two 'commit numbers' as arguments!")
+	FileNotInGroup     = new(83, "File is not tracked in the group!")
+	CLIGrpUntrackErr   = new(84, "group-untrack command accepts 'group name' and 'file path' as arguments!")
+	CLIGrpRenameErr    = new(85, "group-rename command accepts 'group name' and 'new group name' as arguments!")
+	CLIGrpDeleteErr    = new(86, "group-delete command accepts 'group name' and optional --purge as arguments!")
+	CLIGrpStatusErr    = new(87, "group-status command only accepts 'group name' as argument!")
+	NoGroupChanges     = new(88, "No file of the group changed since the current group commit, use --allow-empty to commit anyway!")
+	InvalidCodec       = new(89, "Unknown compression codec, use zlib, gzip, zstd or none!")
+	CLICompressionErr  = new(90, "compression command accepts an optional 'codec' with optional --large 'codec' and --large-size 'size' as arguments!")
+	InvalidDelta       = new(91, "Corrupted binary delta object!")
+	CrossOriginRequest = new(92, "Cross-origin requests can not write to the repository!")
+	CheckedOutRemoved  = new(93, "The checked out commit would be removed from the history, revert to a commit that is kept first!")
 )
@@ -0,0 +1,20 @@
+// Helpers shared by the tests of the qwe packages
+package qwetest
+
+import (
+	"os"
+	"testing"
+
+	cm "github.com/mainak55512/qwe/commit"
+)
+
+// Writes content to a tracked file and commits it with message
+func CommitFile(t testing.TB, filePath, content, message string) {
+	t.Helper()
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, _, err := cm.CommitUnit(filePath, message); err != nil {
+		t.Fatalf("failed to commit file: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package qweutils
+
+import (
+	"bufio"
+	"io"
+)
+
+// Kind of a line that differs between two versions of a text file
+type LineChange int
+
+const (
+	LineChanged LineChange = iota // replaced at the same position
+	LineAdded                     // added after the last line of the previous version
+	LineRemoved                   // removed from the end of the previous version
+)
+
+// Compares two texts line by line at the same positions, the way text commits record changes. fn is called for every
+// line of next that differs from the line of prev at the same position and for every line of prev past the end of next,
+// line numbers start at 1. Returns the number of lines of next.
+func CompareLines(prev, next io.Reader, fn func(change LineChange, line int, prevLine, nextLine string)) (int, error) {
+	prevScanner := bufio.NewScanner(prev)
+	nextScanner := bufio.NewScanner(next)
+
+	line := 0
+	for nextScanner.Scan() {
+		line++
+		if !prevScanner.Scan() {
+			fn(LineAdded, line, "", nextScanner.Text())
+		} else if prevScanner.Text() != nextScanner.Text() {
+			fn(LineChanged, line, prevScanner.Text(), nextScanner.Text())
+		}
+	}
+	if err := nextScanner.Err(); err != nil {
+		return 0, err
+	}
+	lines := line
+	for prevScanner.Scan() {
+		line++
+		fn(LineRemoved, line, prevScanner.Text(), "")
+	}
+	if err := prevScanner.Err(); err != nil {
+		return 0, err
+	}
+	return lines, nil
+}
@@ -0,0 +1,34 @@
+package qweutils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCompareLines(t *testing.T) {
+	tests := []struct {
+		prev, next string
+		lines      int
+		changes    []string
+	}{
+		{"a\nb\n", "a\nb\n", 2, nil},
+		{"a\nb\n", "a\nB\nc\n", 3, []string{"0 2 b B", "1 3  c"}},
+		{"a\nb\n\n", "a\n", 1, []string{"2 2 b ", "2 3  "}},
+		{"a\n", "a\n\n", 2, []string{"1 2  "}},
+		{"", "", 0, nil},
+	}
+	for _, test := range tests {
+		var changes []string
+		lines, err := CompareLines(strings.NewReader(test.prev), strings.NewReader(test.next), func(change LineChange, line int, prevLine, nextLine string) {
+			changes = append(changes, fmt.Sprintf("%d %d %s %s", change, line, prevLine, nextLine))
+		})
+		if err != nil {
+			t.Fatalf("CompareLines(%q, %q) failed: %v", test.prev, test.next, err)
+		}
+		if lines != test.lines || !slices.Equal(changes, test.changes) {
+			t.Errorf("CompareLines(%q, %q) = %d %q, expected %d %q", test.prev, test.next, lines, changes, test.lines, test.changes)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package qweutils
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// Number of files processed at once by group operations, QWE_WORKERS overrides the number of CPUs
+func Workers() int {
+	if n, err := strconv.Atoi(os.Getenv("QWE_WORKERS")); err == nil && n > 0 {
+		return n
+	}
+	return runtime.NumCPU()
+}
+
+// Calls fn for 0..n-1 on at most Workers() goroutines. No new calls are started once a call fails,
+// the error of the lowest index that failed is returned.
+func Parallel(n int, fn func(i int) error) error {
+	workers := min(Workers(), n)
+	errs := make([]error, n)
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := false
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if errs[i] = fn(i); errs[i] != nil {
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range n {
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package qweutils
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallel(t *testing.T) {
+	t.Setenv("QWE_WORKERS", "3")
+	if Workers() != 3 {
+		t.Fatalf("expected 3 workers, got %d", Workers())
+	}
+
+	var running, peak atomic.Int32
+	done := make([]bool, 50)
+	err := Parallel(len(done), func(i int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		done[i] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Parallel() failed: %v", err)
+	}
+	for i, ok := range done {
+		if !ok {
+			t.Errorf("index %d was not processed", i)
+		}
+	}
+	if peak.Load() > 3 {
+		t.Errorf("expected at most 3 concurrent calls, got %d", peak.Load())
+	}
+
+	errFirst, errSecond := errors.New("first"), errors.New("second")
+	err = Parallel(10, func(i int) error {
+		switch i {
+		case 2:
+			return errFirst
+		case 5:
+			return errSecond
+		}
+		return nil
+	})
+	if !errors.Is(err, errFirst) {
+		t.Errorf("expected the error of the lowest index, got %v", err)
+	}
+	if err := Parallel(0, func(int) error { return errFirst }); err != nil {
+		t.Errorf("expected no error without work, got %v", err)
+	}
+}
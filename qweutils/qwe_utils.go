@@ -10,6 +10,7 @@ import (
 	// "io"
 	"io/fs"
 	"os"
+	"os/user"
 	// "unicode"
 )
 
@@ -70,3 +71,14 @@ func FileExists(filePath string) bool {
 	}
 	return false
 }
+
+// Returns the author recorded in commits, QWE_AUTHOR overrides the name of the current user
+func Author() string {
+	if author := os.Getenv("QWE_AUTHOR"); author != "" {
+		return author
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "unknown"
+}
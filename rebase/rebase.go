@@ -9,11 +9,13 @@ import (
 	er "github.com/mainak55512/qwe/qwerror"
 	utl "github.com/mainak55512/qwe/qweutils"
 	res "github.com/mainak55512/qwe/reconstruct"
+	st "github.com/mainak55512/qwe/stash"
 	tr "github.com/mainak55512/qwe/tracker"
 )
 
-// Reverts a file back to its base version
-func Rebase(filePath string) error {
+// Reverts a file back to its base version, uncommitted changes are stashed first if stashDirty is set
+// and are only overwritten if force is set
+func Rebase(filePath string, stashDirty, force bool) error {
 
 	// Get tracker details
 	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
@@ -29,14 +31,28 @@ func Rebase(filePath string) error {
 		return er.FileNotTracked
 	}
 
-	if strings.HasPrefix(val.Base, "_bin_") {
-		if err = bh.RevertBinFile(filePath, val.Base); err != nil {
+	if stashDirty {
+		if err = st.PushIfModified(filePath, "Auto stash before rebase"); err != nil {
+			return err
+		}
+	} else if !force {
+		modified, err := res.Modified(val, filePath)
+		if err != nil {
 			return err
 		}
+		if modified {
+			return fmt.Errorf("%w: %s", er.UncommittedChanges, filePath)
+		}
 	}
 
-	// Reconstruct the file till its base version
-	if err = res.Reconstruct(val, filePath, res.BaseVersion); err != nil {
+	if strings.HasPrefix(val.Base, "_bin_") {
+		// A binary base can be a delta after a prune, it is rebuilt from the objects it is based on
+		err = bh.RevertBinFile(filePath, val.Base)
+	} else {
+		// Reconstruct the file till its base version
+		err = res.Reconstruct(val, filePath, res.BaseVersion)
+	}
+	if err != nil {
 		return err
 	}
 
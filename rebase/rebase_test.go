@@ -0,0 +1,55 @@
+package rebase
+
+import (
+	"os"
+	"testing"
+
+	bh "github.com/mainak55512/qwe/binaryhandler"
+	hs "github.com/mainak55512/qwe/history"
+	in "github.com/mainak55512/qwe/initializer"
+	qt "github.com/mainak55512/qwe/qwetest"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+func TestRebasePrunedBinary(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	content := []byte("\x00\x01")
+	for i := range 4096 {
+		content = append(content, byte(i*7))
+	}
+	version := func(i int) []byte {
+		c := append([]byte{}, content...)
+		c[1000] = byte(i)
+		return c
+	}
+	if err := os.WriteFile("logo.bin", version(0), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := tr.StartTracking("logo.bin"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		qt.CommitFile(t, "logo.bin", string(version(i)), "Change")
+	}
+	if err := hs.Prune("logo.bin", 1); err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	if base := tracker[utl.Hasher("logo.bin")].Base; !bh.IsDelta(base) {
+		t.Fatalf("expected the pruned base to be a delta, got %s", base)
+	}
+
+	if err := Rebase("logo.bin", false, false); err != nil {
+		t.Fatalf("Rebase() failed: %v", err)
+	}
+	if got, _ := os.ReadFile("logo.bin"); string(got) != string(version(2)) {
+		t.Errorf("expected the working file to hold the base version, got %d bytes", len(got))
+	}
+}
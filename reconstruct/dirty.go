@@ -0,0 +1,103 @@
+package reconstruct
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	bh "github.com/mainak55512/qwe/binaryhandler"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// Checks if the working file has changes that are not recorded in its checked out version.
+// A missing working file is not treated as modified as there is nothing to lose.
+func Modified(val tr.Tracker, filePath string) (bool, error) {
+	if !utl.FileExists(filePath) {
+		return false, nil
+	}
+
+	if strings.HasPrefix(val.Base, "_bin_") {
+		src, err := bh.Open(val.Current)
+		if err != nil {
+			return false, err
+		}
+		defer src.Close()
+		working, err := os.Open(filePath)
+		if err != nil {
+			return false, err
+		}
+		defer working.Close()
+		isEq, err := bh.SameContent(src, working)
+		if err != nil {
+			return false, err
+		}
+		return !isEq, nil
+	}
+
+	target := ".qwe/_object/_dirty_" + utl.Hasher(fmt.Sprintf("%s%d", filePath, time.Now().UnixNano()))
+	defer os.Remove(target)
+
+	current, _ := val.CommitID(val.Current)
+	if err := Reconstruct(val, target, current); err != nil {
+		return false, err
+	}
+	return linesDiffer(target, filePath)
+}
+
+// Compares two text files line by line the same way commits are recorded
+func linesDiffer(file_one, file_two string) (bool, error) {
+	file_1, err := os.Open(file_one)
+	if err != nil {
+		return false, err
+	}
+	defer file_1.Close()
+
+	file_2, err := os.Open(file_two)
+	if err != nil {
+		return false, err
+	}
+	defer file_2.Close()
+
+	scanner_1 := bufio.NewScanner(file_1)
+	scanner_2 := bufio.NewScanner(file_2)
+	for {
+		more_1 := scanner_1.Scan()
+		more_2 := scanner_2.Scan()
+		if more_1 != more_2 {
+			return true, nil
+		}
+		if !more_1 {
+			return false, nil
+		}
+		if !bytes.Equal(scanner_1.Bytes(), scanner_2.Bytes()) {
+			return true, nil
+		}
+	}
+}
+
+// Returns the content of a committed version of a text or binary file, BaseVersion returns the base content
+func Content(val tr.Tracker, commitID int) ([]byte, error) {
+	if commitID != BaseVersion && (commitID < 0 || commitID > len(val.Versions)-1) {
+		return nil, er.InvalidCommitNo
+	}
+
+	if strings.HasPrefix(val.Base, "_bin_") {
+		objID := val.Base
+		if commitID != BaseVersion {
+			objID = val.Versions[commitID].UID
+		}
+		return bh.ReadObject(objID)
+	}
+
+	target := ".qwe/_object/_content_" + utl.Hasher(fmt.Sprintf("%s%d", val.Base, time.Now().UnixNano()))
+	defer os.Remove(target)
+	if err := Reconstruct(val, target, commitID); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(target)
+}
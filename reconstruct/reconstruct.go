@@ -29,15 +29,12 @@ func Reconstruct(val tr.Tracker, target string, commitID int) error {
 	// }
 	buf := make([]byte, 1024)
 
-	// Decompress the base varient
-	if err := cp.DecompressFile(".qwe/_object/" + val.Base); err != nil {
-		return err
-	}
-
-	base_content, err := os.Open(".qwe/_object/" + val.Base)
+	// The base varient is decompressed while it is copied, objects stay compressed on disk
+	base_content, err := cp.Open(".qwe/_object/" + val.Base)
 	if err != nil {
 		return err
 	}
+	defer base_content.Close()
 
 	target_content, err := os.Create(target)
 	if err != nil {
@@ -47,15 +44,12 @@ func Reconstruct(val tr.Tracker, target string, commitID int) error {
 	// Copy the content from base varient to the file
 	_, err = io.CopyBuffer(target_content, base_content, buf)
 	if err != nil {
+		target_content.Close()
 		return err
 	}
-	base_content.Close()
-
-	// Compress the base varient
-	if err = cp.CompressFile(".qwe/_object/" + val.Base); err != nil {
+	if err = target_content.Close(); err != nil {
 		return err
 	}
-	target_content.Close()
 
 	// if commitID is -2, that means only base varient is needed
 	if commitID == BaseVersion {
@@ -70,88 +64,110 @@ func Reconstruct(val tr.Tracker, target string, commitID int) error {
 			break
 		}
 
-		if err = cp.DecompressFile(".qwe/_object/" + elem.UID); err != nil {
+		if err = applyCommit(elem.UID, target); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		diff_file, err := os.Open(".qwe/_object/" + elem.UID)
-		if err != nil {
+// Reconstructs every version of a text file one after another into target, starting from the base version.
+// fn is called after each version is reconstructed, with BaseVersion as commitID for the base version.
+func EachVersion(val tr.Tracker, target string, fn func(commitID int) error) error {
+	if err := Reconstruct(val, target, BaseVersion); err != nil {
+		return err
+	}
+	if err := fn(BaseVersion); err != nil {
+		return err
+	}
+	for i, elem := range val.Versions {
+		if err := applyCommit(elem.UID, target); err != nil {
 			return err
 		}
-		// defer diff_file.Close()
-		diff_scanner := bufio.NewScanner(diff_file)
-
-		base_file, err := os.Open(target)
-		if err != nil {
+		if err := fn(i); err != nil {
 			return err
 		}
-		// defer base_file.Close()
-		base_scanner := bufio.NewScanner(base_file)
+	}
+	return nil
+}
 
-		var output string
+// Applies the changes recorded in a commit file on to the target file
+func applyCommit(uid, target string) error {
+	diff_file, err := cp.Open(".qwe/_object/" + uid)
+	if err != nil {
+		return err
+	}
+	diff_scanner := bufio.NewScanner(diff_file)
 
-		// This will retrieve the line number from the commit file,
-		// reconstructed file should only have these many lines in it.
-		diff_scanner.Scan()
+	base_file, err := os.Open(target)
+	if err != nil {
+		diff_file.Close()
+		return err
+	}
+	base_scanner := bufio.NewScanner(base_file)
 
-		total_lines, err := strconv.Atoi(diff_scanner.Text())
-		if err != nil {
-			return err
-		}
+	var output strings.Builder
 
-		// Retrieving a line from commit file
-		diff_scanner.Scan()
-		idx := 1
-		for i := 0; i < total_lines; i++ {
-
-			// Retrieve a line from base file
-			base_scanner.Scan()
-
-			// Split the line from the commit file
-			// to get the line number where the change occured and the content as string
-			comp := strings.Split(diff_scanner.Text(), " @@@ ")
-			line_number, _ := strconv.Atoi(comp[0])
-
-			// if current line number of the base file is same as the retrieved line number
-			// from the commit file, then decompress the string from the commit file and
-			// add it to the output string and scan the next line from the commit file,
-			// else add the line from the base file to the output string
-			if idx == line_number {
-				dec_str, err := utl.ConvStrDec(comp[1])
-				if err != nil {
-					return err
-				}
-				output += dec_str + "\n"
-				diff_scanner.Scan()
-			} else {
-				output += base_scanner.Text() + "\n"
-			}
-			idx++
-		}
+	// This will retrieve the line number from the commit file,
+	// reconstructed file should only have these many lines in it.
+	diff_scanner.Scan()
 
+	total_lines, err := strconv.Atoi(diff_scanner.Text())
+	if err != nil {
 		diff_file.Close()
-
-		// Compress the commit file
-		if err = cp.CompressFile(".qwe/_object/" + elem.UID); err != nil {
-			return err
-		}
 		base_file.Close()
+		return err
+	}
 
-		output_content, err := os.Create(target)
-		if err != nil {
-			return err
+	// Retrieving a line from commit file
+	diff_scanner.Scan()
+	idx := 1
+	for i := 0; i < total_lines; i++ {
+
+		// Retrieve a line from base file
+		base_scanner.Scan()
+
+		// Split the line from the commit file
+		// to get the line number where the change occured and the content as string
+		comp := strings.Split(diff_scanner.Text(), " @@@ ")
+		line_number, _ := strconv.Atoi(comp[0])
+
+		// if current line number of the base file is same as the retrieved line number
+		// from the commit file, then decompress the string from the commit file and
+		// add it to the output string and scan the next line from the commit file,
+		// else add the line from the base file to the output string
+		if idx == line_number {
+			dec_str, err := utl.ConvStrDec(comp[1])
+			if err != nil {
+				diff_file.Close()
+				base_file.Close()
+				return err
+			}
+			output.WriteString(dec_str + "\n")
+			diff_scanner.Scan()
+		} else {
+			output.WriteString(base_scanner.Text() + "\n")
 		}
+		idx++
+	}
 
-		// Write all the changes to the file
-		output_writer := bufio.NewWriter(output_content)
-		_, err = output_writer.WriteString(output)
-		if err != nil {
-			return er.BaseWriteErr
-		}
-		if err = output_writer.Flush(); err != nil {
-			return er.OutputWriteErr
-		}
-		output_content.Close()
+	diff_file.Close()
+	base_file.Close()
+
+	output_content, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+
+	// Write all the changes to the file
+	output_writer := bufio.NewWriter(output_content)
+	_, err = output_writer.WriteString(output.String())
+	if err != nil {
+		return er.BaseWriteErr
+	}
+	if err = output_writer.Flush(); err != nil {
+		return er.OutputWriteErr
 	}
+	output_content.Close()
 	return nil
 }
@@ -0,0 +1,91 @@
+package reconstruct
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cp "github.com/mainak55512/qwe/compressor"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// Writes a text commit object in the format CommitUnit records
+func writeDelta(t *testing.T, objID string, lines int, changed map[int]string) {
+	t.Helper()
+	var delta strings.Builder
+	fmt.Fprintf(&delta, "%d\n", lines)
+	for line := 1; line <= lines; line++ {
+		if text, ok := changed[line]; ok {
+			fmt.Fprintf(&delta, "%d @@@ %s\n", line, utl.ConvStrEnc(text))
+		}
+	}
+	if err := cp.WriteFile(filepath.Join(".qwe", "_object", objID), []byte(delta.String())); err != nil {
+		t.Fatalf("failed to write commit object: %v", err)
+	}
+}
+
+func TestEachVersion(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(filepath.Join(".qwe", "_object"), 0o755); err != nil {
+		t.Fatalf("failed to create object directory: %v", err)
+	}
+	if err := cp.WriteFile(filepath.Join(".qwe", "_object", "_base_b"), []byte("one\ntwo\nthree\n")); err != nil {
+		t.Fatalf("failed to write base object: %v", err)
+	}
+	writeDelta(t, "c0", 3, map[int]string{2: "TWO"})
+	writeDelta(t, "c1", 5, map[int]string{4: "four", 5: "five @@@ with marker"})
+	writeDelta(t, "c2", 2, nil)
+
+	val := tr.Tracker{
+		Base:     "_base_b",
+		Current:  "c2",
+		Versions: []tr.VersionDetails{{UID: "c0"}, {UID: "c1"}, {UID: "c2"}},
+	}
+	want := map[int]string{
+		BaseVersion: "one\ntwo\nthree\n",
+		0:           "one\nTWO\nthree\n",
+		1:           "one\nTWO\nthree\nfour\nfive @@@ with marker\n",
+		2:           "one\nTWO\n",
+	}
+
+	target := filepath.Join(".qwe", "_object", "_content_test")
+	var order []int
+	err := EachVersion(val, target, func(commitID int) error {
+		order = append(order, commitID)
+		got, err := os.ReadFile(target)
+		if err != nil {
+			return err
+		}
+		if string(got) != want[commitID] {
+			t.Errorf("commit %d: expected %q, got %q", commitID, want[commitID], got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachVersion() failed: %v", err)
+	}
+	if fmt.Sprint(order) != fmt.Sprint([]int{BaseVersion, 0, 1, 2}) {
+		t.Errorf("expected every version in commit order, got %v", order)
+	}
+
+	// Reconstructing a single version gives the same content
+	for commitID, content := range want {
+		got, err := Content(val, commitID)
+		if err != nil || string(got) != content {
+			t.Errorf("Content(%d) = %q, %v, want %q", commitID, got, err, content)
+		}
+	}
+
+	stop := fmt.Errorf("stop")
+	if err := EachVersion(val, target, func(commitID int) error {
+		if commitID == 0 {
+			return stop
+		}
+		return nil
+	}); err != stop {
+		t.Errorf("expected EachVersion to return the error of fn, got %v", err)
+	}
+}
@@ -0,0 +1,320 @@
+package remote
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	tw "text/tabwriter"
+
+	bh "github.com/mainak55512/qwe/binaryhandler"
+	cf "github.com/mainak55512/qwe/config"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+const lockFile = "_lock"
+
+// Returns the .qwe directory of a remote, the path may point to the directory itself or to its parent
+func qweDir(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if utl.FileExists(filepath.Join(path, "_tracker.qwe")) {
+		return path, nil
+	}
+	if utl.FileExists(filepath.Join(path, tr.QweDir, "_tracker.qwe")) {
+		return filepath.Join(path, tr.QweDir), nil
+	}
+	return "", fmt.Errorf("%w: %s", er.InvalidRemote, path)
+}
+
+// Creates an empty store that can be used as a remote
+func initStore(path string) error {
+	if utl.FolderExists(path) {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("%w: %s", er.RemoteNotEmpty, path)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(path, "_object"), os.ModePerm); err != nil {
+		return er.RepoInitError
+	}
+	empty := tr.History{
+		Files:  make(tr.TrackerSchema),
+		Groups: make(tr.GroupTrackerSchema),
+		Paths:  make(tr.TrackFiles),
+	}
+	return empty.SaveTo(path)
+}
+
+// Adds a remote, the path is another .qwe directory or its parent. With initialize an empty store is created at the path.
+func Add(name, path string, initialize bool) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	config, err := cf.Load()
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Remotes[name]; ok {
+		return er.RemoteExists
+	}
+	if initialize {
+		if err = initStore(path); err != nil {
+			return err
+		}
+	}
+	dir, err := qweDir(path)
+	if err != nil {
+		return err
+	}
+	local, err := filepath.Abs(tr.QweDir)
+	if err != nil {
+		return err
+	}
+	if dir == local {
+		return fmt.Errorf("%w: %s", er.InvalidRemote, path)
+	}
+
+	config.Remotes[name] = dir
+	if err = config.Save(); err != nil {
+		return err
+	}
+	fmt.Println("Added remote", name, "at", dir)
+	return nil
+}
+
+// Removes a remote from the configuration, the remote store is not touched
+func Remove(name string) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	config, err := cf.Load()
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Remotes[name]; !ok {
+		return fmt.Errorf("%w: %s", er.InvalidRemote, name)
+	}
+	delete(config.Remotes, name)
+	if err = config.Save(); err != nil {
+		return err
+	}
+	fmt.Println("Removed remote", name)
+	return nil
+}
+
+// Prints the configured remotes
+func List() error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	config, err := cf.Load()
+	if err != nil {
+		return err
+	}
+	if len(config.Remotes) == 0 {
+		fmt.Println("No remote configured")
+		return nil
+	}
+	names := make([]string, 0, len(config.Remotes))
+	for name := range config.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := new(tw.Writer)
+	w.Init(os.Stdout, 0, 0, 1, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\n", name, config.Remotes[name])
+	}
+	w.Flush()
+	return nil
+}
+
+// Returns the .qwe directory of a configured remote
+func resolve(name string) (string, error) {
+	if !utl.QweIsInWorkingDir() {
+		return "", er.RepoNotFound
+	}
+	config, err := cf.Load()
+	if err != nil {
+		return "", err
+	}
+	path, ok := config.Remotes[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", er.InvalidRemote, name)
+	}
+	return qweDir(path)
+}
+
+// Locks a remote so concurrent pushes and pulls see and write consistent trackers, returns the unlock function
+func lock(dir string) (func(), error) {
+	path := filepath.Join(dir, lockFile)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("%w: remove %s if no other push or pull is running", er.RemoteLocked, path)
+		}
+		return nil, err
+	}
+	fmt.Fprintf(file, "%d %s\n", os.Getpid(), time.Now().String()[:16])
+	file.Close()
+	return func() { os.Remove(path) }, nil
+}
+
+// Copies the objects missing in the destination object directory, returns the number of copied objects.
+// Binary deltas are copied after the objects they are based on. Ids listed by the source trackers or delta
+// headers that could escape the object directory are rejected.
+func copyObjects(objects []string, srcDir, destDir string) (int, error) {
+	copied := 0
+	for _, objID := range objects {
+		if !tr.ValidObjectID(objID) {
+			return copied, fmt.Errorf("%w: invalid object %q", er.InvalidRemote, objID)
+		}
+	}
+	objects, err := bh.WithDependencies(filepath.Join(srcDir, "_object"), objects)
+	if err != nil {
+		return copied, err
+	}
+	for _, objID := range objects {
+		if !tr.ValidObjectID(objID) {
+			return copied, fmt.Errorf("%w: invalid object %q", er.InvalidRemote, objID)
+		}
+		dest := filepath.Join(destDir, "_object", objID)
+		if utl.FileExists(dest) {
+			continue
+		}
+		if err := copyFile(filepath.Join(srcDir, "_object", objID), dest); err != nil {
+			return copied, fmt.Errorf("%w: %s", er.MissingObject, objID)
+		}
+		copied++
+	}
+	return copied, nil
+}
+
+// Copies a file through a temporary name so an interrupted copy never leaves a partial object
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err = out.Close(); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	if err = os.Chmod(out.Name(), 0644); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	return os.Rename(out.Name(), dest)
+}
+
+// Sends the history of the repository, or of the targets only, to a remote.
+// Files and groups whose remote history has commits that are not present locally are rejected.
+func Push(name string, targets []string) error {
+	dir, err := resolve(name)
+	if err != nil {
+		return err
+	}
+	local, err := tr.LoadHistory(tr.QweDir)
+	if err != nil {
+		return err
+	}
+	if len(targets) > 0 {
+		if local, err = local.SelectTargets(targets); err != nil {
+			return err
+		}
+	}
+
+	unlock, err := lock(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	remote, err := tr.LoadHistory(dir)
+	if err != nil {
+		return err
+	}
+	report, objects := remote.Merge(local)
+
+	// Objects are published before the trackers that refer to them
+	copied, err := copyObjects(objects, tr.QweDir, dir)
+	if err != nil {
+		return err
+	}
+	if len(report.Added)+len(report.Updated) > 0 {
+		if err = remote.SaveTo(dir); err != nil {
+			return err
+		}
+	}
+
+	report.Print()
+	fmt.Printf("Pushed %d objects to %s\n", copied, name)
+	return nil
+}
+
+// Fetches the history of a remote, or of the targets only, and merges it into the repository.
+// Working files are not modified.
+func Pull(name string, targets []string) error {
+	dir, err := resolve(name)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lock(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	remote, err := tr.LoadHistory(dir)
+	if err != nil {
+		return err
+	}
+	if len(targets) > 0 {
+		if remote, err = remote.SelectTargets(targets); err != nil {
+			return err
+		}
+	}
+	local, err := tr.LoadHistory(tr.QweDir)
+	if err != nil {
+		return err
+	}
+	report, objects := local.Merge(remote)
+
+	copied, err := copyObjects(objects, dir, tr.QweDir)
+	if err != nil {
+		return err
+	}
+	if len(report.Added)+len(report.Updated) > 0 {
+		if err = local.Save(); err != nil {
+			return err
+		}
+	}
+
+	report.Print()
+	fmt.Printf("Pulled %d objects from %s\n", copied, name)
+	return nil
+}
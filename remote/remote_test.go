@@ -0,0 +1,160 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cp "github.com/mainak55512/qwe/compressor"
+	in "github.com/mainak55512/qwe/initializer"
+	er "github.com/mainak55512/qwe/qwerror"
+	qt "github.com/mainak55512/qwe/qwetest"
+	utl "github.com/mainak55512/qwe/qweutils"
+	rc "github.com/mainak55512/qwe/recover"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+func commitCount(t *testing.T, qweDir, filePath string) int {
+	t.Helper()
+	h, err := tr.LoadHistory(qweDir)
+	if err != nil {
+		t.Fatalf("failed to load history: %v", err)
+	}
+	return len(h.Files[utl.Hasher(filePath)].Versions)
+}
+
+func TestPushPull(t *testing.T) {
+	alice, bob := t.TempDir(), t.TempDir()
+	central := filepath.Join(t.TempDir(), "central")
+
+	t.Chdir(alice)
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte("base\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := tr.StartTracking("notes.txt"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	if err := Add("origin", central, true); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if err := Add("origin", central, false); err == nil {
+		t.Error("expected error for existing remote")
+	}
+	if err := Push("origin", nil); err != nil {
+		t.Fatalf("Push() failed: %v", err)
+	}
+
+	t.Chdir(bob)
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := Add("origin", central, false); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if err := Pull("origin", nil); err != nil {
+		t.Fatalf("Pull() failed: %v", err)
+	}
+	if err := rc.Recover("notes.txt"); err != nil {
+		t.Fatalf("failed to recover pulled file: %v", err)
+	}
+	qt.CommitFile(t, "notes.txt", "bob\n", "Bob's change")
+	if err := Push("origin", []string{"notes.txt"}); err != nil {
+		t.Fatalf("Push() failed: %v", err)
+	}
+	if got := commitCount(t, central, "notes.txt"); got != 1 {
+		t.Fatalf("expected 1 commit on the remote, got %d", got)
+	}
+
+	// Alice has not pulled Bob's commit, her push must not clobber it
+	t.Chdir(alice)
+	qt.CommitFile(t, "notes.txt", "alice\n", "Alice's change")
+	if err := Push("origin", nil); err != nil {
+		t.Fatalf("Push() failed: %v", err)
+	}
+	h, err := tr.LoadHistory(central)
+	if err != nil {
+		t.Fatalf("failed to load history: %v", err)
+	}
+	if msg := h.Files[utl.Hasher("notes.txt")].Versions[0].CommitMessage; msg != "Bob's change" {
+		t.Errorf("remote history was overwritten, got commit %q", msg)
+	}
+
+	// Bob fast-forwards after another commit
+	t.Chdir(bob)
+	qt.CommitFile(t, "notes.txt", "bob again\n", "Bob's second change")
+	if err := Push("origin", nil); err != nil {
+		t.Fatalf("Push() failed: %v", err)
+	}
+	if got := commitCount(t, central, "notes.txt"); got != 2 {
+		t.Errorf("expected 2 commits on the remote, got %d", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(central, lockFile), nil, 0o644); err != nil {
+		t.Fatalf("failed to create lock: %v", err)
+	}
+	if err := Pull("origin", nil); err == nil {
+		t.Error("expected error for locked remote")
+	}
+}
+
+func TestPullRejectsEscapingObjects(t *testing.T) {
+	alice, bob := t.TempDir(), t.TempDir()
+	central := filepath.Join(t.TempDir(), "central")
+
+	t.Chdir(alice)
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte("base\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := tr.StartTracking("notes.txt"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	if err := Add("origin", central, true); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if err := Push("origin", nil); err != nil {
+		t.Fatalf("Push() failed: %v", err)
+	}
+
+	// A remote tracker that refers to an object outside of the object directory
+	h, err := tr.LoadHistory(central)
+	if err != nil {
+		t.Fatalf("failed to load history: %v", err)
+	}
+	fileID := utl.Hasher("notes.txt")
+	val := h.Files[fileID]
+	val.Versions = append(val.Versions, tr.VersionDetails{UID: "../escaped"})
+	val.Current = "../escaped"
+	h.Files[fileID] = val
+	content, err := json.Marshal(h.Files)
+	if err != nil {
+		t.Fatalf("failed to marshal tracker: %v", err)
+	}
+	if err := cp.WriteFile(filepath.Join(central, "_tracker.qwe"), content); err != nil {
+		t.Fatalf("failed to write tracker: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(central, "escaped"), []byte("outside\n"), 0o644); err != nil {
+		t.Fatalf("failed to write object: %v", err)
+	}
+
+	t.Chdir(bob)
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := Add("origin", central, false); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if err := Pull("origin", nil); !errors.Is(err, er.InvalidRemote) {
+		t.Errorf("expected InvalidRemote, got %v", err)
+	}
+	if utl.FileExists(filepath.Join(tr.QweDir, "escaped")) {
+		t.Error("pull wrote an object outside of the object directory")
+	}
+}
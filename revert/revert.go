@@ -2,20 +2,25 @@ package revert
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	// cp "github.com/mainak55512/qwe/compressor"
 	bh "github.com/mainak55512/qwe/binaryhandler"
 	er "github.com/mainak55512/qwe/qwerror"
 	utl "github.com/mainak55512/qwe/qweutils"
-	rb "github.com/mainak55512/qwe/rebase"
 	res "github.com/mainak55512/qwe/reconstruct"
+	st "github.com/mainak55512/qwe/stash"
 	tr "github.com/mainak55512/qwe/tracker"
 )
 
-// Reverts the file to a specific version
-func Revert(commitNumber int, filePath string) error {
+// Reverts the file to a specific version, uncommitted changes are stashed first if stashDirty is set
+// and are only overwritten if force is set
+func Revert(commitNumber int, filePath string, stashDirty, force bool) error {
 
 	// Check if the file is present before reverting
 	if exists := utl.FileExists(filePath); !exists {
@@ -40,6 +45,20 @@ func Revert(commitNumber int, filePath string) error {
 			return fmt.Errorf("File %s was never committed, use 'rebase' command to revert back to base version", filePath)
 		}
 
+		if stashDirty {
+			if err = st.PushIfModified(filePath, "Auto stash before revert"); err != nil {
+				return err
+			}
+		} else if !force {
+			modified, err := res.Modified(val, filePath)
+			if err != nil {
+				return err
+			}
+			if modified {
+				return fmt.Errorf("%w: %s", er.UncommittedChanges, filePath)
+			}
+		}
+
 		if strings.HasPrefix(val.Base, "_bin_") {
 			commitID := commitNumber
 			if commitNumber == -1 {
@@ -82,8 +101,11 @@ func Revert(commitNumber int, filePath string) error {
 	return nil
 }
 
-// Revert a group to any specific version
-func RevertGroup(groupName string, commitID int) error {
+// Revert a group to any specific version, uncommitted changes are stashed first if stashDirty is set
+// and are only overwritten if force is set. The revert is all-or-nothing: the content of every file is built first,
+// and if writing a file or saving the trackers fails the working files are put back as they were.
+// Trackers are read and written once, the files are reconstructed and written concurrently.
+func RevertGroup(groupName string, commitID int, stashDirty, force bool) error {
 
 	// Get group tracker
 	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
@@ -106,18 +128,115 @@ func RevertGroup(groupName string, commitID int) error {
 	// Get all the file details of that specific version
 	files := val.Versions[val.VersionOrder[commitID]].Files
 
-	for k := range files {
-		commitNumber := files[k].CommitNumber
-
-		if commitNumber >= 0 { // commit number +ve means normal tracked file
-			if err := Revert(commitNumber, files[k].FileName); err != nil {
-				return err
+	if stashDirty {
+		if err = st.PushIfModified(groupName, "Auto stash before group revert"); err != nil {
+			return err
+		}
+	} else if !force {
+		// Check every file before reverting any of them, so the group is never left half reverted
+		tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+		if err != nil {
+			return err
+		}
+		var modifiedFiles []string
+		for k := range files {
+			f, ok := tracker[k]
+			if !ok {
+				continue
 			}
-		} else if commitNumber == -2 { // commit number -2 means file is just tracked in qwe, no other commits are present, hence need to revert to base version
-			if err := rb.Rebase(files[k].FileName); err != nil {
+			modified, err := res.Modified(f, files[k].FileName)
+			if err != nil {
 				return err
 			}
+			if modified {
+				modifiedFiles = append(modifiedFiles, files[k].FileName)
+			}
 		}
+		if len(modifiedFiles) > 0 {
+			sort.Strings(modifiedFiles)
+			return fmt.Errorf("%w: %s", er.UncommittedChanges, strings.Join(modifiedFiles, ", "))
+		}
+	}
+
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return err
+	}
+	previous, err := json.MarshalIndent(tracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+	previousGroup, err := json.MarshalIndent(groupTracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+
+	var reverts []*fileRevert
+	var unresolved []string
+	for k, f := range files {
+		fileVal, ok := tracker[k]
+		if !ok {
+			continue
+		}
+		commitNumber, ok := fileVal.CommitID(f.UID)
+		if !ok {
+			unresolved = append(unresolved, f.FileName)
+			continue
+		}
+		reverts = append(reverts, &fileRevert{fileID: k, filePath: f.FileName, commitNumber: commitNumber})
+	}
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		fmt.Printf("Warning: group commit %d has no commit of %s, left unchanged\n", commitID, strings.Join(unresolved, ", "))
+	}
+	sort.Slice(reverts, func(i, j int) bool {
+		return reverts[i].filePath < reverts[j].filePath
+	})
+
+	// Build the content of every file concurrently before any of them is written
+	err = utl.Parallel(len(reverts), func(i int) error {
+		r := reverts[i]
+		content, err := res.Content(tracker[r.fileID], r.commitNumber)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, r.filePath)
+		}
+		r.content = content
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range reverts {
+		fileVal := tracker[r.fileID]
+		fileVal.Current = files[r.fileID].UID
+		tracker[r.fileID] = fileVal
+	}
+
+	// Write every file, restoring the ones already written if any of them fails
+	rollback := func() {
+		for _, r := range reverts {
+			if !r.written {
+				continue
+			}
+			if restoreErr := r.restore(); restoreErr != nil {
+				fmt.Printf("Warning: failed to restore %s: %v\n", r.filePath, restoreErr)
+			}
+		}
+	}
+	err = utl.Parallel(len(reverts), func(i int) error {
+		r := reverts[i]
+		if err := r.backup(); err != nil {
+			return err
+		}
+		r.written = true
+		if err := r.write(); err != nil {
+			return fmt.Errorf("%w: %s", er.OutputWriteErr, r.filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		rollback()
+		return err
 	}
 
 	// Update current version with newly checked out version
@@ -126,14 +245,77 @@ func RevertGroup(groupName string, commitID int) error {
 	// Update group tracker with new values
 	groupTracker[groupID] = val
 
-	marshalContent, err := json.MarshalIndent(groupTracker, "", " ")
+	trackerContent, err := json.MarshalIndent(tracker, "", " ")
 	if err != nil {
+		rollback()
 		return er.CommitUnsuccessful
 	}
+	groupTrackerContent, err := json.MarshalIndent(groupTracker, "", " ")
+	if err != nil {
+		rollback()
+		return er.CommitUnsuccessful
+	}
+
+	// Update the trackers and write them before reporting success, also when the command defers its writes.
+	// The files and both trackers are restored if that fails.
+	if err = tr.SaveTracker(tr.FileTrackerType, trackerContent); err == nil {
+		if err = tr.SaveTracker(tr.GroupTrackerType, groupTrackerContent); err == nil {
+			err = tr.SyncSession()
+		}
+	}
+	if err != nil {
+		rollback()
+		if restoreErr := tr.RestoreTrackers(previous, previousGroup); restoreErr != nil {
+			fmt.Printf("Warning: failed to restore the trackers: %v\n", restoreErr)
+		}
+		return err
+	}
+	fmt.Printf("Successfully reverted group %s back to commit %d: %d files reverted\n", groupName, commitID, len(reverts))
+	return nil
+}
+
+// A working file of a group revert together with what it held before, so it can be put back
+type fileRevert struct {
+	fileID       string
+	filePath     string
+	commitNumber int
+	content      []byte
 
-	// Update the tracker
-	if err = tr.SaveTracker(1, marshalContent); err != nil {
+	written bool // set once the file is backed up and about to be written
+	existed bool
+	old     []byte
+	mode    os.FileMode
+}
+
+func (r *fileRevert) backup() error {
+	r.mode = 0644
+	info, err := os.Stat(r.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
 		return err
 	}
+	if r.old, err = os.ReadFile(r.filePath); err != nil {
+		return err
+	}
+	r.existed, r.mode = true, info.Mode().Perm()
 	return nil
 }
+
+// Writes the reverted content, a missing file is restored
+func (r *fileRevert) write() error {
+	if dir := filepath.Dir(r.filePath); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(r.filePath, r.content, r.mode)
+}
+
+func (r *fileRevert) restore() error {
+	if !r.existed {
+		return os.Remove(r.filePath)
+	}
+	return os.WriteFile(r.filePath, r.old, r.mode)
+}
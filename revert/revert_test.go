@@ -0,0 +1,310 @@
+package revert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	cm "github.com/mainak55512/qwe/commit"
+	in "github.com/mainak55512/qwe/initializer"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// commitVersions tracks the file and commits every content in order
+func commitVersions(t *testing.T, filePath string, contents ...string) {
+	t.Helper()
+	for i, content := range contents {
+		if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if i == 0 {
+			if _, err := tr.StartTracking(filePath); err != nil {
+				t.Fatalf("failed to track file: %v", err)
+			}
+			continue
+		}
+		if _, _, err := cm.CommitUnit(filePath, content); err != nil {
+			t.Fatalf("failed to commit file: %v", err)
+		}
+	}
+}
+
+func TestRevertRefusesUncommittedChanges(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+
+	textPath := "notes.txt"
+	commitVersions(t, textPath, "base\n", "one\n", "two\n")
+	binPath := "image.bin"
+	commitVersions(t, binPath, "\x00\x01base", "\x00\x01one", "\x00\x01two")
+
+	for _, filePath := range []string{textPath, binPath} {
+		if err := os.WriteFile(filePath, []byte("\x00unsaved"), 0o644); err != nil {
+			t.Fatalf("failed to modify file: %v", err)
+		}
+
+		if err := Revert(0, filePath, false, false); !errors.Is(err, er.UncommittedChanges) {
+			t.Fatalf("expected UncommittedChanges for %s, got %v", filePath, err)
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+		if string(content) != "\x00unsaved" {
+			t.Errorf("uncommitted changes of %s were overwritten: %q", filePath, content)
+		}
+
+		if err := Revert(0, filePath, false, true); err != nil {
+			t.Fatalf("forced revert of %s failed: %v", filePath, err)
+		}
+	}
+
+	content, err := os.ReadFile(textPath)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(content) != "one\n" {
+		t.Errorf("expected forced revert to commit 0, got %q", content)
+	}
+
+	// A clean working copy is reverted without --force
+	if err := Revert(1, textPath, false, false); err != nil {
+		t.Errorf("revert of clean file failed: %v", err)
+	}
+}
+
+func TestRevertGroupListsModifiedFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	for _, filePath := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filePath, []byte("base\n"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := tr.StartGroupTracking("docs", []string{"a.txt", "b.txt"}); err != nil {
+		t.Fatalf("failed to track files in group: %v", err)
+	}
+	for _, filePath := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filePath, []byte("changed\n"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := cm.CommitGroup("docs", "changed", false, false); err != nil {
+		t.Fatalf("failed to commit group: %v", err)
+	}
+
+	if err := os.WriteFile("b.txt", []byte("unsaved\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	err := RevertGroup("docs", 0, false, false)
+	if !errors.Is(err, er.UncommittedChanges) {
+		t.Fatalf("expected UncommittedChanges, got %v", err)
+	}
+	if content, _ := os.ReadFile("a.txt"); string(content) != "changed\n" {
+		t.Errorf("clean file was reverted before the group was checked: %q", content)
+	}
+
+	if err := RevertGroup("docs", 0, true, false); err != nil {
+		t.Fatalf("group revert with stash failed: %v", err)
+	}
+	for _, filePath := range []string{"a.txt", "b.txt"} {
+		if content, _ := os.ReadFile(filePath); string(content) != "base\n" {
+			t.Errorf("%s was not reverted to base, got %q", filePath, content)
+		}
+	}
+}
+
+func TestRevertGroupRollsBackOnFailure(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	if err := os.Mkdir("dir", 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	for _, filePath := range []string{"a.txt", "dir/c.txt"} {
+		if err := os.WriteFile(filePath, []byte("base\n"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := tr.StartGroupTracking("docs", []string{"a.txt", "dir/c.txt"}); err != nil {
+		t.Fatalf("failed to track files in group: %v", err)
+	}
+	if err := os.WriteFile("a.txt", []byte("changed\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := cm.CommitGroup("docs", "changed", false, false); err != nil {
+		t.Fatalf("failed to commit group: %v", err)
+	}
+
+	// dir/c.txt can not be written once dir is a file, a.txt is reverted first and must be put back
+	if err := os.RemoveAll("dir"); err != nil {
+		t.Fatalf("failed to remove directory: %v", err)
+	}
+	if err := os.WriteFile("dir", []byte("not a directory\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := RevertGroup("docs", 0, false, true); err == nil {
+		t.Fatal("expected the group revert to fail")
+	}
+	if content, _ := os.ReadFile("a.txt"); string(content) != "changed\n" {
+		t.Errorf("a.txt was not restored after the failed revert, got %q", content)
+	}
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	val := tracker[utl.Hasher("a.txt")]
+	if val.Current != val.Versions[0].UID {
+		t.Errorf("expected a.txt to stay at commit 0 in the tracker, got %s", val.Current)
+	}
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read group tracker: %v", err)
+	}
+	if gr := groupTracker[utl.Hasher("docs")]; gr.Current != gr.VersionOrder[1] {
+		t.Errorf("expected the group to stay at commit 1, got %s", gr.Current)
+	}
+
+	// Once the path can be written again the missing file is restored
+	if err := os.Remove("dir"); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	if err := RevertGroup("docs", 0, false, true); err != nil {
+		t.Fatalf("group revert failed: %v", err)
+	}
+	for _, filePath := range []string{"a.txt", "dir/c.txt"} {
+		if content, _ := os.ReadFile(filePath); string(content) != "base\n" {
+			t.Errorf("%s was not reverted to base, got %q", filePath, content)
+		}
+	}
+}
+
+func TestGroupCommitAndRevertManyFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("QWE_WORKERS", "4")
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	var files []string
+	for i := range 40 {
+		filePath := fmt.Sprintf("file%02d.txt", i)
+		if err := os.WriteFile(filePath, []byte("base\n"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		files = append(files, filePath)
+	}
+	if err := tr.StartGroupTracking("docs", files); err != nil {
+		t.Fatalf("failed to track files in group: %v", err)
+	}
+	for i, filePath := range files {
+		if i%2 == 0 {
+			if err := os.WriteFile(filePath, []byte("base\nchanged "+filePath+"\n"), 0o644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+		}
+	}
+	if err := cm.CommitGroup("docs", "changed", false, false); err != nil {
+		t.Fatalf("failed to commit group: %v", err)
+	}
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	for i, filePath := range files {
+		if n := len(tracker[utl.Hasher(filePath)].Versions); n != 1-i%2 {
+			t.Errorf("%s: expected %d commits, got %d", filePath, 1-i%2, n)
+		}
+	}
+
+	if err := RevertGroup("docs", 0, false, false); err != nil {
+		t.Fatalf("group revert failed: %v", err)
+	}
+	for _, filePath := range files {
+		if content, _ := os.ReadFile(filePath); string(content) != "base\n" {
+			t.Errorf("%s was not reverted to base, got %q", filePath, content)
+		}
+	}
+	if err := RevertGroup("docs", 1, false, false); err != nil {
+		t.Fatalf("group revert failed: %v", err)
+	}
+	for i, filePath := range files {
+		want := "base\n"
+		if i%2 == 0 {
+			want = "base\nchanged " + filePath + "\n"
+		}
+		if content, _ := os.ReadFile(filePath); string(content) != want {
+			t.Errorf("%s: expected %q, got %q", filePath, want, content)
+		}
+	}
+}
+
+func TestRevertGroupWarnsAboutUnresolvedCommits(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	for _, filePath := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filePath, []byte("base\n"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := tr.StartGroupTracking("docs", []string{"a.txt", "b.txt"}); err != nil {
+		t.Fatalf("failed to track files in group: %v", err)
+	}
+
+	// The first group version refers to a commit of b.txt that is not part of its history
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read group tracker: %v", err)
+	}
+	gr := groupTracker[utl.Hasher("docs")]
+	version := gr.Versions[gr.VersionOrder[0]]
+	version.Files[utl.Hasher("b.txt")] = tr.FileDetails{FileName: "b.txt", UID: "missing"}
+	content, err := json.Marshal(groupTracker)
+	if err != nil {
+		t.Fatalf("failed to marshal group tracker: %v", err)
+	}
+	if err := tr.SaveTracker(tr.GroupTrackerType, content); err != nil {
+		t.Fatalf("failed to save group tracker: %v", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	revertErr := RevertGroup("docs", 0, false, true)
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+	if revertErr != nil {
+		t.Fatalf("group revert failed: %v", revertErr)
+	}
+	if !strings.Contains(string(out), "Warning: group commit 0 has no commit of b.txt") {
+		t.Errorf("expected a warning about b.txt, got %q", out)
+	}
+}
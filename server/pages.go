@@ -0,0 +1,82 @@
+package server
+
+import "html/template"
+
+const layout = `{{define "head"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>qwe{{if .}} - {{.}}{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: 0.3em 1em; text-align: left; border-bottom: 1px solid #ddd; }
+pre { background: #f6f6f6; padding: 1em; }
+.del { color: #b00; }
+.add { color: #070; }
+</style>
+</head>
+<body>
+<p><a href="/">qwe</a></p>
+{{end}}
+{{define "foot"}}</body>
+</html>
+{{end}}`
+
+var funcs = template.FuncMap{
+	"dec": func(i int) int { return i - 1 },
+}
+
+func page(body string) *template.Template {
+	return template.Must(template.Must(template.New("layout").Funcs(funcs).Parse(layout)).New("page").Parse(body))
+}
+
+var indexPage = page(`{{template "head" ""}}
+<h2>Tracked files</h2>
+<table>
+<tr><th>File</th><th>Commits</th><th>Current</th></tr>
+{{range .Files}}<tr><td><a href="/file?path={{.Path}}">{{.Path}}</a>{{if .Binary}} (binary){{end}}</td><td>{{.Commits}}</td><td>{{if eq .CurrentCommit -2}}base{{else}}{{.CurrentCommit}}{{end}}</td></tr>
+{{end}}</table>
+<h2>Groups</h2>
+<table>
+<tr><th>Group</th><th>Versions</th><th>Current</th></tr>
+{{range .Groups}}<tr><td><a href="/group?name={{.Name}}">{{.Name}}</a></td><td>{{.Versions}}</td><td>{{.CurrentCommit}}</td></tr>
+{{end}}</table>
+{{template "foot"}}`)
+
+var filePage = page(`{{template "head" .Path}}
+<h2>{{.Path}}</h2>
+<p><a href="/api/content?path={{.Path}}&commit=base">base version</a> | <a href="/diff?path={{.Path}}">uncommitted changes</a></p>
+<table>
+<tr><th>ID</th><th>Commit Message</th><th>Author</th><th>Time Stamp</th><th></th></tr>
+{{$path := .Path}}{{range $i, $v := .Versions}}<tr><td>{{$i}}</td><td>{{$v.CommitMessage}}</td><td>{{$v.Author}}</td><td>{{$v.TimeStamp}}</td>
+<td><a href="/api/content?path={{$path}}&commit={{$i}}">content</a>{{if $i}} | <a href="/diff?path={{$path}}&from={{dec $i}}&to={{$i}}">diff</a>{{end}}</td></tr>
+{{end}}</table>
+{{template "foot"}}`)
+
+var groupPage = page(`{{template "head" .Name}}
+<h2>Group {{.Name}}</h2>
+<table>
+<tr><th>ID</th><th>Commit Message</th><th>Author</th><th>Time Stamp</th><th>Files</th></tr>
+{{$name := .Name}}{{range $i, $v := .Versions}}<tr><td><a href="/group/version?name={{$name}}&id={{$i}}">{{$i}}</a></td><td>{{$v.CommitMessage}}</td><td>{{$v.Author}}</td><td>{{$v.TimeStamp}}</td><td>{{len $v.Files}}</td></tr>
+{{end}}</table>
+{{template "foot"}}`)
+
+var groupVersionPage = page(`{{template "head" .Info.Name}}
+<h2>Group <a href="/group?name={{.Info.Name}}">{{.Info.Name}}</a> version {{.Info.CommitID}}</h2>
+<p>{{.Info.Version.CommitMessage}}</p>
+<table>
+<tr><th>File</th><th>Commit</th></tr>
+{{range .Files}}<tr><td><a href="/file?path={{.Name}}">{{.Name}}</a></td><td><a href="/api/content?path={{.Name}}&commit={{.Commit}}">{{.Commit}}</a></td></tr>
+{{end}}</table>
+{{template "foot"}}`)
+
+var diffPage = page(`{{template "head" .Path}}
+<h2>Diff of <a href="/file?path={{.Path}}">{{.Path}}</a> {{if .From}}{{.From}} .. {{.To}}{{else}}uncommitted changes{{end}}</h2>
+{{with .Result}}{{if .Binary}}<p>{{if .Changed}}File content changed!{{else}}File content is same!{{end}}</p>
+{{else if not .Changes}}<p>No Change!</p>
+{{else}}<pre>{{range .Changes}}<span class="del">{{.Prev}}</span>
+<span class="add">{{.Curr}}</span>
+
+{{end}}</pre>{{end}}{{end}}
+{{template "foot"}}`)
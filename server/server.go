@@ -0,0 +1,498 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	cm "github.com/mainak55512/qwe/commit"
+	"github.com/mainak55512/qwe/diff"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	res "github.com/mainak55512/qwe/reconstruct"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// Tracked file shown in the file list
+type FileInfo struct {
+	Path          string `json:"path"`
+	Binary        bool   `json:"binary"`
+	Commits       int    `json:"commits"`
+	CurrentCommit int    `json:"current_commit"` // -2 is the base version
+}
+
+// Group shown in the group list
+type GroupInfo struct {
+	Name          string `json:"name"`
+	Versions      int    `json:"versions"`
+	CurrentCommit int    `json:"current_commit"`
+}
+
+type server struct {
+	mu       sync.Mutex // repository files are decompressed in place, so requests are served one at a time
+	writable bool
+	origins  http.CrossOriginProtection
+}
+
+// Returns the handler serving the JSON API and the HTML pages of the repository in the working directory.
+// Commits can only be created through the API if writable is set.
+func NewHandler(writable bool) http.Handler {
+	s := &server{writable: writable}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/files", s.guard(s.apiFiles))
+	mux.HandleFunc("/api/file", s.guard(s.apiFile))
+	mux.HandleFunc("/api/groups", s.guard(s.apiGroups))
+	mux.HandleFunc("/api/group", s.guard(s.apiGroup))
+	mux.HandleFunc("/api/group/version", s.guard(s.apiGroupVersion))
+	mux.HandleFunc("/api/content", s.guard(s.apiContent))
+	mux.HandleFunc("/api/diff", s.guard(s.apiDiff))
+	mux.HandleFunc("/api/commit", s.guard(s.apiCommit))
+	mux.HandleFunc("/api/group-commit", s.guard(s.apiGroupCommit))
+	mux.HandleFunc("/", s.guard(s.pageIndex))
+	mux.HandleFunc("/file", s.guard(s.pageFile))
+	mux.HandleFunc("/group", s.guard(s.pageGroup))
+	mux.HandleFunc("/group/version", s.guard(s.pageGroupVersion))
+	mux.HandleFunc("/diff", s.guard(s.pageDiff))
+	return mux
+}
+
+// Serves the repository in the working directory until the server fails
+func Serve(addr string, writable bool) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	mode := "read-only"
+	if writable {
+		mode = "writable"
+	}
+	fmt.Printf("Serving qwe repository on http://%s (%s)\n", addr, mode)
+	return http.ListenAndServe(addr, NewHandler(writable))
+}
+
+// Serializes requests and only allows writes on a writable server. Browsers send simple cross-site POST requests
+// without asking, so writes need a JSON body and must not come from another origin.
+func (s *server) guard(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if !s.writable {
+				writeError(w, er.ServerReadOnly)
+				return
+			}
+			if err := s.origins.Check(r); err != nil {
+				writeError(w, er.CrossOriginRequest)
+				return
+			}
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeError(w, fmt.Errorf("%w: expected Content-Type application/json", er.InvalidRequest))
+				return
+			}
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		handler(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+// Maps qwe errors to HTTP status codes
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, er.FileNotTracked), errors.Is(err, er.InvalidGroup):
+		status = http.StatusNotFound
+	case errors.Is(err, er.InvalidCommitNo), errors.Is(err, er.InvalidRequest), errors.Is(err, er.NoFileOrDiff),
+		errors.Is(err, er.NoGroupChanges):
+		status = http.StatusBadRequest
+	case errors.Is(err, er.ServerReadOnly), errors.Is(err, er.CrossOriginRequest):
+		status = http.StatusForbidden
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// Returns a required query parameter
+func param(r *http.Request, name string) (string, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return "", fmt.Errorf("%w: missing parameter %s", er.InvalidRequest, name)
+	}
+	return value, nil
+}
+
+// Parses a commit id parameter, 'base' is the base version of a file
+func commitParam(r *http.Request, name string) (int, error) {
+	value, err := param(r, name)
+	if err != nil {
+		return 0, err
+	}
+	if value == "base" {
+		return res.BaseVersion, nil
+	}
+	commitID, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, er.InvalidCommitNo
+	}
+	return commitID, nil
+}
+
+func files() ([]FileInfo, error) {
+	h, err := tr.LoadHistory(tr.QweDir)
+	if err != nil {
+		return nil, err
+	}
+	list := []FileInfo{}
+	for fileID, val := range h.Files {
+		p, ok := h.Paths[fileID]
+		if !ok {
+			continue
+		}
+		current, _ := val.CommitID(val.Current)
+		list = append(list, FileInfo{
+			Path:          p.FilePath,
+			Binary:        strings.HasPrefix(val.Base, "_bin_"),
+			Commits:       len(val.Versions),
+			CurrentCommit: current,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Path < list[j].Path
+	})
+	return list, nil
+}
+
+func groups() ([]GroupInfo, error) {
+	h, err := tr.LoadHistory(tr.QweDir)
+	if err != nil {
+		return nil, err
+	}
+	list := []GroupInfo{}
+	for _, gr := range h.Groups {
+		current := 0
+		for i, k := range gr.VersionOrder {
+			if k == gr.Current {
+				current = i
+			}
+		}
+		list = append(list, GroupInfo{Name: gr.GroupName, Versions: len(gr.VersionOrder), CurrentCommit: current})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// Returns the content of a file at a commit
+func content(filePath string, commitID int) ([]byte, error) {
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return nil, err
+	}
+	val, ok := tracker[utl.Hasher(filePath)]
+	if !ok {
+		return nil, er.FileNotTracked
+	}
+	return res.Content(val, commitID)
+}
+
+// Returns the difference between two commits of a file, from 'uncommitted' compares the working file
+func compare(r *http.Request) (*diff.Result, error) {
+	filePath, err := param(r, "path")
+	if err != nil {
+		return nil, err
+	}
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if (from == "") != (to == "") {
+		return nil, fmt.Errorf("%w: from and to must be supplied together", er.InvalidRequest)
+	}
+	versions, err := cm.CommitList(filePath)
+	if err != nil {
+		return nil, err
+	}
+	// Validate the commit ids, the diff works on positions in the commit list
+	for _, id := range []string{from, to} {
+		if id == "" || id == "uncommitted" {
+			continue
+		}
+		commitID, err := strconv.Atoi(id)
+		if err != nil || commitID < 0 || commitID > len(versions)-1 {
+			return nil, er.InvalidCommitNo
+		}
+	}
+	if to == "uncommitted" {
+		return nil, er.InvalidCommitNo
+	}
+	return diff.Compare(filePath, from, to)
+}
+
+func (s *server) apiFiles(w http.ResponseWriter, r *http.Request) {
+	list, err := files()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, list)
+}
+
+func (s *server) apiFile(w http.ResponseWriter, r *http.Request) {
+	filePath, err := param(r, "path")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	versions, err := cm.CommitList(filePath)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, versions)
+}
+
+func (s *server) apiGroups(w http.ResponseWriter, r *http.Request) {
+	list, err := groups()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, list)
+}
+
+func (s *server) apiGroup(w http.ResponseWriter, r *http.Request) {
+	groupName, err := param(r, "name")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	versions, err := cm.GroupCommitList(groupName)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, versions)
+}
+
+func (s *server) apiGroupVersion(w http.ResponseWriter, r *http.Request) {
+	groupName, err := param(r, "name")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	commitID, err := commitParam(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	info, err := cm.GroupVersion(groupName, commitID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, info)
+}
+
+func (s *server) apiContent(w http.ResponseWriter, r *http.Request) {
+	filePath, err := param(r, "path")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	commitID, err := commitParam(r, "commit")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	data, err := content(filePath, commitID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// Text is never rendered by the browser, tracked files may contain markup
+	contentType := http.DetectContentType(data)
+	if strings.HasPrefix(contentType, "text/") {
+		contentType = "text/plain; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(data)
+}
+
+func (s *server) apiDiff(w http.ResponseWriter, r *http.Request) {
+	result, err := compare(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, result)
+}
+
+// Request body of the commit endpoints
+type commitRequest struct {
+	Path    string `json:"path"`
+	Group   string `json:"group"`
+	Message string `json:"message"`
+}
+
+func readCommitRequest(r *http.Request) (*commitRequest, error) {
+	var req commitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Message == "" {
+		return nil, fmt.Errorf("%w: expected a JSON body with a message", er.InvalidRequest)
+	}
+	return &req, nil
+}
+
+func (s *server) apiCommit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := readCommitRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	_, commitID, err := cm.CommitUnit(req.Path, req.Message)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, map[string]int{"commit_id": commitID})
+}
+
+func (s *server) apiGroupCommit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := readCommitRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err = cm.CommitGroup(req.Group, req.Message, false, false); err != nil {
+		writeError(w, err)
+		return
+	}
+	versions, err := cm.GroupCommitList(req.Group)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, map[string]int{"commit_id": len(versions) - 1})
+}
+
+// Renders a page, errors are shown as plain text
+func render(w http.ResponseWriter, page *template.Template, data any, err error) {
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, er.FileNotTracked) || errors.Is(err, er.InvalidGroup) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, er.InvalidCommitNo) || errors.Is(err, er.InvalidRequest) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := page.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *server) pageIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	fileList, err := files()
+	if err != nil {
+		render(w, indexPage, nil, err)
+		return
+	}
+	groupList, err := groups()
+	render(w, indexPage, map[string]any{"Files": fileList, "Groups": groupList}, err)
+}
+
+func (s *server) pageFile(w http.ResponseWriter, r *http.Request) {
+	filePath, err := param(r, "path")
+	if err != nil {
+		render(w, filePage, nil, err)
+		return
+	}
+	versions, err := cm.CommitList(filePath)
+	render(w, filePage, map[string]any{"Path": filePath, "Versions": versions}, err)
+}
+
+func (s *server) pageGroup(w http.ResponseWriter, r *http.Request) {
+	groupName, err := param(r, "name")
+	if err != nil {
+		render(w, groupPage, nil, err)
+		return
+	}
+	versions, err := cm.GroupCommitList(groupName)
+	render(w, groupPage, map[string]any{"Name": groupName, "Versions": versions}, err)
+}
+
+func (s *server) pageGroupVersion(w http.ResponseWriter, r *http.Request) {
+	groupName, err := param(r, "name")
+	if err != nil {
+		render(w, groupVersionPage, nil, err)
+		return
+	}
+	commitID, err := commitParam(r, "id")
+	if err != nil {
+		render(w, groupVersionPage, nil, err)
+		return
+	}
+	info, err := cm.GroupVersion(groupName, commitID)
+	if err != nil {
+		render(w, groupVersionPage, nil, err)
+		return
+	}
+
+	// Files sorted by name, commit ids as accepted by the content endpoint
+	type groupFile struct {
+		Name   string
+		Commit string
+	}
+	var list []groupFile
+	for fileID, f := range info.Version.Files {
+		commitID, ok := info.Commits[fileID]
+		if !ok {
+			continue
+		}
+		commit := strconv.Itoa(commitID)
+		if commitID == res.BaseVersion {
+			commit = "base"
+		}
+		list = append(list, groupFile{Name: f.FileName, Commit: commit})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	render(w, groupVersionPage, map[string]any{"Info": info, "Files": list}, nil)
+}
+
+func (s *server) pageDiff(w http.ResponseWriter, r *http.Request) {
+	result, err := compare(r)
+	render(w, diffPage, map[string]any{
+		"Path":   r.URL.Query().Get("path"),
+		"From":   r.URL.Query().Get("from"),
+		"To":     r.URL.Query().Get("to"),
+		"Result": result,
+	}, err)
+}
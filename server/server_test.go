@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	cm "github.com/mainak55512/qwe/commit"
+	in "github.com/mainak55512/qwe/initializer"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+func TestHandler(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte("one\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := tr.StartTracking("notes.txt"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	if _, _, err := cm.CommitUnit("notes.txt", "Add two"); err != nil {
+		t.Fatalf("failed to commit file: %v", err)
+	}
+
+	srv := httptest.NewServer(NewHandler(false))
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("/api/files")
+	var files []FileInfo
+	if err := json.Unmarshal([]byte(body), &files); err != nil || status != http.StatusOK {
+		t.Fatalf("unexpected file list %d: %s", status, body)
+	}
+	if len(files) != 1 || files[0].Path != "notes.txt" || files[0].Commits != 1 {
+		t.Errorf("unexpected file list: %+v", files)
+	}
+
+	tests := []struct {
+		path     string
+		status   int
+		contains string
+	}{
+		{"/api/file?path=notes.txt", http.StatusOK, "Add two"},
+		{"/api/content?path=notes.txt&commit=base", http.StatusOK, "one\n"},
+		{"/api/content?path=notes.txt&commit=0", http.StatusOK, "one\ntwo\n"},
+		{"/api/content?path=notes.txt&commit=7", http.StatusBadRequest, "error"},
+		{"/api/diff?path=notes.txt", http.StatusOK, `"changed":false`},
+		{"/api/file?path=missing.txt", http.StatusNotFound, "error"},
+		{"/api/group?name=missing", http.StatusNotFound, "error"},
+		{"/", http.StatusOK, "notes.txt"},
+		{"/file?path=notes.txt", http.StatusOK, "Add two"},
+	}
+	for _, tt := range tests {
+		status, body := get(tt.path)
+		if status != tt.status || !strings.Contains(body, tt.contains) {
+			t.Errorf("GET %s: expected %d containing %q, got %d: %s", tt.path, tt.status, tt.contains, status, body)
+		}
+	}
+
+	resp, err := http.Post(srv.URL+"/api/commit", "application/json", strings.NewReader(`{"path":"notes.txt","message":"m"}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected read-only server to refuse commits, got %d", resp.StatusCode)
+	}
+}
+
+func TestWritableHandlerRejectsCrossOriginWrites(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte("one\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := tr.StartTracking("notes.txt"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	if err := os.WriteFile("notes.txt", []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+
+	srv := httptest.NewServer(NewHandler(true))
+	defer srv.Close()
+
+	post := func(contentType string, header map[string]string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/commit", strings.NewReader(`{"path":"notes.txt","message":"Add two"}`))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		header      map[string]string
+		status      int
+	}{
+		{"form body", "text/plain", nil, http.StatusBadRequest},
+		{"foreign origin", "application/json", map[string]string{"Origin": "http://evil.example"}, http.StatusForbidden},
+		{"cross-site fetch", "application/json", map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"same origin", "application/json; charset=utf-8", map[string]string{"Origin": srv.URL}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if status := post(tt.contentType, tt.header); status != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, status)
+		}
+	}
+	versions, err := cm.CommitList("notes.txt")
+	if err != nil || len(versions) != 1 {
+		t.Errorf("expected only the same-origin request to commit, got %d commits, %v", len(versions), err)
+	}
+}
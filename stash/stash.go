@@ -0,0 +1,313 @@
+package stash
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tw "text/tabwriter"
+
+	bh "github.com/mainak55512/qwe/binaryhandler"
+	cp "github.com/mainak55512/qwe/compressor"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	res "github.com/mainak55512/qwe/reconstruct"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+const stashPath = ".qwe/_stash.qwe"
+
+// Working copy of a single file saved in a stash entry
+type StashFile struct {
+	FileName string `json:"file_name"`
+	ObjID    string `json:"obj_id"`
+}
+
+// A stash entry, stashes of a group keep all the modified files of the group together
+type Entry struct {
+	Target    string      `json:"target"`
+	IsGroup   bool        `json:"is_group"`
+	Message   string      `json:"message"`
+	TimeStamp string      `json:"time_stamp"`
+	Files     []StashFile `json:"files"`
+}
+
+// Stash entries, the latest entry is kept at the end
+type StashSchema []Entry
+
+// Reads the stash entries from _stash.qwe, repositories without stashes have an empty list
+func load() (StashSchema, error) {
+	var stashes StashSchema
+
+	if !utl.FileExists(stashPath) {
+		return stashes, nil
+	}
+
+	content, err := cp.ReadFile(stashPath)
+	if err != nil {
+		return nil, er.TrackerAccessErr
+	}
+	if err = json.Unmarshal(content, &stashes); err != nil {
+		return nil, er.TrackerParseErr
+	}
+	return stashes, nil
+}
+
+// Updates _stash.qwe file
+func save(stashes StashSchema) error {
+	content, err := json.MarshalIndent(stashes, "", " ")
+	if err != nil {
+		return er.TrackerWriteErr
+	}
+	if err = cp.WriteFile(stashPath, content); err != nil {
+		return er.TrackerWriteErr
+	}
+	return nil
+}
+
+// Returns the objects holding the working copies of the stash entries
+func Objects() ([]string, error) {
+	stashes, err := load()
+	if err != nil {
+		return nil, err
+	}
+	var objects []string
+	for _, entry := range stashes {
+		for _, f := range entry.Files {
+			objects = append(objects, f.ObjID)
+		}
+	}
+	return objects, nil
+}
+
+// Converts the stash id shown to the user to the position in the stash list, 0 being the latest stash
+func position(stashes StashSchema, stashID int) (int, error) {
+	if stashID < 0 || stashID > len(stashes)-1 {
+		return 0, er.InvalidStash
+	}
+	return len(stashes) - 1 - stashID, nil
+}
+
+// Restores the working file to the checked out version of the file
+func checkout(val tr.Tracker, filePath string) error {
+	if strings.HasPrefix(val.Base, "_bin_") {
+		return bh.RevertBinFile(filePath, val.Current)
+	}
+	current, _ := val.CommitID(val.Current)
+	return res.Reconstruct(val, filePath, current)
+}
+
+// Saves the working content of a file to a stash object
+func saveObject(filePath string) (string, error) {
+	objID := "_stash_" + utl.Hasher(fmt.Sprintf("%s%d", filePath, time.Now().UnixNano()))
+	target := ".qwe/_object/" + objID
+
+	src, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	if err = cp.Copy(target, src); err != nil {
+		return "", err
+	}
+	return objID, nil
+}
+
+// Saves uncommitted changes of a file or of all the files of a group and restores their checked out versions
+func Push(target, message string) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return err
+	}
+
+	entry := Entry{
+		Target:    target,
+		Message:   message,
+		TimeStamp: time.Now().String()[:16],
+	}
+
+	var filePaths []string
+	if _, ok := tracker[utl.Hasher(target)]; ok {
+		filePaths = append(filePaths, target)
+	} else {
+		_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+		if err != nil {
+			return err
+		}
+		gr, ok := groupTracker[utl.Hasher(target)]
+		if !ok {
+			return er.FileNotTracked
+		}
+		entry.IsGroup = true
+		for _, f := range gr.Versions[gr.Current].Files {
+			filePaths = append(filePaths, f.FileName)
+		}
+	}
+
+	if entry.Message == "" {
+		entry.Message = "WIP on " + target
+	}
+
+	for _, filePath := range filePaths {
+		val, ok := tracker[utl.Hasher(filePath)]
+		if !ok {
+			continue
+		}
+		modified, err := res.Modified(val, filePath)
+		if err != nil {
+			return err
+		}
+		if !modified {
+			continue
+		}
+		objID, err := saveObject(filePath)
+		if err != nil {
+			return err
+		}
+		entry.Files = append(entry.Files, StashFile{
+			FileName: filePath,
+			ObjID:    objID,
+		})
+	}
+
+	if len(entry.Files) == 0 {
+		return er.NothingToStash
+	}
+
+	stashes, err := load()
+	if err != nil {
+		return err
+	}
+	stashes = append(stashes, entry)
+	if err = save(stashes); err != nil {
+		return err
+	}
+
+	// Working files are restored only after the stash is saved, so changes are never lost
+	for _, f := range entry.Files {
+		if err = checkout(tracker[utl.Hasher(f.FileName)], f.FileName); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Saved uncommitted changes of", target, "as stash 0")
+	return nil
+}
+
+// Stashes uncommitted changes of a file or group if there are any, used before overwriting working files
+func PushIfModified(target, message string) error {
+	if err := Push(target, message); err != nil && !errors.Is(err, er.NothingToStash) {
+		return err
+	}
+	return nil
+}
+
+// Restores the working content saved in a stash and removes the stash
+func Pop(stashID int) error {
+	stashes, err := load()
+	if err != nil {
+		return err
+	}
+	pos, err := position(stashes, stashID)
+	if err != nil {
+		return err
+	}
+	entry := stashes[pos]
+
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return err
+	}
+
+	// Refuse to overwrite changes that are not committed or stashed
+	for _, f := range entry.Files {
+		val, ok := tracker[utl.Hasher(f.FileName)]
+		if !ok {
+			continue
+		}
+		modified, err := res.Modified(val, f.FileName)
+		if err != nil {
+			return err
+		}
+		if modified {
+			return fmt.Errorf("%w: %s", er.StashConflict, f.FileName)
+		}
+	}
+
+	for _, f := range entry.Files {
+		if err = bh.RevertBinFile(f.FileName, f.ObjID); err != nil {
+			return err
+		}
+	}
+
+	if err = drop(stashes, pos); err != nil {
+		return err
+	}
+	fmt.Println("Restored stash", stashID, "on", entry.Target)
+	return nil
+}
+
+// Removes a stash without applying it
+func Drop(stashID int) error {
+	stashes, err := load()
+	if err != nil {
+		return err
+	}
+	pos, err := position(stashes, stashID)
+	if err != nil {
+		return err
+	}
+	if err = drop(stashes, pos); err != nil {
+		return err
+	}
+	fmt.Println("Dropped stash", stashID)
+	return nil
+}
+
+func drop(stashes StashSchema, pos int) error {
+	entry := stashes[pos]
+	stashes = append(stashes[:pos], stashes[pos+1:]...)
+	if err := save(stashes); err != nil {
+		return err
+	}
+	for _, f := range entry.Files {
+		os.Remove(".qwe/_object/" + f.ObjID)
+	}
+	return nil
+}
+
+// Prints the saved stashes, latest first
+func List() error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+
+	stashes, err := load()
+	if err != nil {
+		return err
+	}
+	if len(stashes) == 0 {
+		fmt.Println("No stashes found!")
+		return nil
+	}
+
+	w := new(tw.Writer)
+	w.Init(os.Stdout, 0, 0, 0, ' ', tw.TabIndent)
+	for i := len(stashes) - 1; i >= 0; i-- {
+		e := stashes[i]
+		fmt.Fprintf(w, "\nStash ID:\t%d\nTarget:\t%s\nMessage:\t%s\nTime Stamp:\t%s\n", len(stashes)-1-i, e.Target, e.Message, e.TimeStamp)
+		for _, f := range e.Files {
+			fmt.Fprintf(w, "File:\t%s\n", f.FileName)
+		}
+	}
+	w.Flush()
+	return nil
+}
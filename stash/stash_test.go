@@ -0,0 +1,121 @@
+package stash
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	cm "github.com/mainak55512/qwe/commit"
+	in "github.com/mainak55512/qwe/initializer"
+	er "github.com/mainak55512/qwe/qwerror"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// setupRepo initializes a repository in a temp directory with one committed file
+func setupRepo(t *testing.T, filePath, content string) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := tr.StartTracking(filePath); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+}
+
+func TestPushAndPop(t *testing.T) {
+	filePath := "notes.txt"
+	setupRepo(t, filePath, "first line\n")
+
+	if err := os.WriteFile(filePath, []byte("first line\nsecond line\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	if _, _, err := cm.CommitUnit(filePath, "second line"); err != nil {
+		t.Fatalf("failed to commit file: %v", err)
+	}
+
+	if err := os.WriteFile(filePath, []byte("first line\nwork in progress\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	if err := Push(filePath, ""); err != nil {
+		t.Fatalf("Push() failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(content) != "first line\nsecond line\n" {
+		t.Errorf("working file was not restored to the checked out version, got %q", content)
+	}
+
+	stashes, err := load()
+	if err != nil {
+		t.Fatalf("failed to load stashes: %v", err)
+	}
+	if len(stashes) != 1 || len(stashes[0].Files) != 1 {
+		t.Fatalf("expected one stash with one file, got %+v", stashes)
+	}
+	objID := stashes[0].Files[0].ObjID
+
+	if err := Push(filePath, ""); !errors.Is(err, er.NothingToStash) {
+		t.Errorf("expected NothingToStash for clean file, got %v", err)
+	}
+
+	if err := Pop(0); err != nil {
+		t.Fatalf("Pop() failed: %v", err)
+	}
+	content, err = os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(content) != "first line\nwork in progress\n" {
+		t.Errorf("stashed content was not restored, got %q", content)
+	}
+	if _, err := os.Stat(".qwe/_object/" + objID); !os.IsNotExist(err) {
+		t.Errorf("stash object was not removed after pop: %v", err)
+	}
+	if err := Pop(0); !errors.Is(err, er.InvalidStash) {
+		t.Errorf("expected InvalidStash on empty stash list, got %v", err)
+	}
+}
+
+func TestPopRefusesUncommittedChanges(t *testing.T) {
+	filePath := "notes.txt"
+	setupRepo(t, filePath, "base\n")
+
+	if err := os.WriteFile(filePath, []byte("stashed\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	if err := Push(filePath, "experiment"); err != nil {
+		t.Fatalf("Push() failed: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte("other change\n"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+
+	if err := Pop(0); !errors.Is(err, er.StashConflict) {
+		t.Fatalf("expected StashConflict, got %v", err)
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(content) != "other change\n" {
+		t.Errorf("uncommitted changes were overwritten, got %q", content)
+	}
+
+	if err := Drop(0); err != nil {
+		t.Fatalf("Drop() failed: %v", err)
+	}
+	stashes, err := load()
+	if err != nil {
+		t.Fatalf("failed to load stashes: %v", err)
+	}
+	if len(stashes) != 0 {
+		t.Errorf("expected no stashes after drop, got %d", len(stashes))
+	}
+}
@@ -0,0 +1,159 @@
+package tracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+)
+
+func saveGroupTracker(groupTracker GroupTrackerSchema) error {
+	content, err := json.MarshalIndent(groupTracker, "", " ")
+	if err != nil {
+		return er.CommitUnsuccessful
+	}
+	return SaveTracker(GroupTrackerType, content)
+}
+
+// Leaves a file out of the next commit of a group. Earlier group versions and the history of the file are kept.
+func StopGroupTracking(groupName, filePath string) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	_, groupTracker, err := GetTracker(GroupTrackerType)
+	if err != nil {
+		return err
+	}
+	groupID := utl.Hasher(groupName)
+	gr, ok := groupTracker[groupID]
+	if !ok {
+		return er.InvalidGroup
+	}
+
+	fileID := utl.Hasher(filePath)
+	if _, ok := gr.Versions[gr.Current].Files[fileID]; !ok {
+		return fmt.Errorf("%w: %s", er.FileNotInGroup, filePath)
+	}
+	if slices.Contains(gr.Untracked, fileID) {
+		fmt.Println(filePath, "is already left out of the next commit of group", groupName)
+		return nil
+	}
+	gr.Untracked = append(gr.Untracked, fileID)
+	groupTracker[groupID] = gr
+
+	if err = saveGroupTracker(groupTracker); err != nil {
+		return err
+	}
+	fmt.Println("Stopped tracking", filePath, "for group", groupName+", it will not be part of the next group commit")
+	return nil
+}
+
+// Renames a group, its versions are kept
+func RenameGroup(groupName, newName string) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	_, groupTracker, err := GetTracker(GroupTrackerType)
+	if err != nil {
+		return err
+	}
+	groupID := utl.Hasher(groupName)
+	gr, ok := groupTracker[groupID]
+	if !ok {
+		return er.InvalidGroup
+	}
+	newID := utl.Hasher(newName)
+	if _, ok := groupTracker[newID]; ok {
+		return er.GrpAlreadyTracked
+	}
+
+	gr.GroupName = newName
+	delete(groupTracker, groupID)
+	groupTracker[newID] = gr
+
+	if err = saveGroupTracker(groupTracker); err != nil {
+		return err
+	}
+	fmt.Println("Renamed group", groupName, "to", newName)
+	return nil
+}
+
+// Deletes a group and its versions. The files of the group stay tracked unless purge is set,
+// then files that are not part of any other group are untracked as well.
+func DeleteGroup(groupName string, purge bool) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	_, groupTracker, err := GetTracker(GroupTrackerType)
+	if err != nil {
+		return err
+	}
+	groupID := utl.Hasher(groupName)
+	gr, ok := groupTracker[groupID]
+	if !ok {
+		return er.InvalidGroup
+	}
+	delete(groupTracker, groupID)
+
+	if !purge {
+		if err = saveGroupTracker(groupTracker); err != nil {
+			return err
+		}
+		fmt.Println("Deleted group", groupName)
+		return nil
+	}
+
+	// The group and the files only it tracked are removed with a single write of each tracker
+	tracker, _, err := GetTracker(FileTrackerType)
+	if err != nil {
+		return err
+	}
+	trackedFiles, err := loadOrScanTrackedFiles(filepath.Join(QweDir, FileName))
+	if err != nil {
+		return err
+	}
+	var untracked []string
+	for _, fileID := range gr.FileIDs() {
+		if _, ok := tracker[fileID]; !ok {
+			continue
+		}
+		filePath := gr.fileName(fileID)
+		if other := memberOf(groupTracker, fileID); other != "" {
+			fmt.Println("Kept the history of", filePath+", it is part of group", other)
+			continue
+		}
+		untrack(tracker, groupTracker, trackedFiles, fileID)
+		untracked = append(untracked, filePath)
+	}
+	if err = saveUntracked(tracker, groupTracker, trackedFiles); err != nil {
+		return err
+	}
+	fmt.Println("Deleted group", groupName)
+	for _, filePath := range untracked {
+		fmt.Println("Stopped tracking", filePath)
+	}
+	return nil
+}
+
+// Returns the latest path a file was recorded with in the group
+func (gr *GroupTracker) fileName(fileID string) string {
+	for i := len(gr.VersionOrder) - 1; i >= 0; i-- {
+		if f, ok := gr.Versions[gr.VersionOrder[i]].Files[fileID]; ok {
+			return f.FileName
+		}
+	}
+	return ""
+}
+
+// Returns the name of a group any version of which contains the file, or an empty string
+func memberOf(groupTracker GroupTrackerSchema, fileID string) string {
+	for _, gr := range groupTracker {
+		if slices.Contains(gr.FileIDs(), fileID) {
+			return gr.GroupName
+		}
+	}
+	return ""
+}
@@ -0,0 +1,287 @@
+package tracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cp "github.com/mainak55512/qwe/compressor"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+)
+
+// Tracker state of a repository, or of a part of it, that is moved between repositories
+type History struct {
+	Files  TrackerSchema      `json:"files"`
+	Groups GroupTrackerSchema `json:"groups"`
+	Paths  TrackFiles         `json:"paths"`
+}
+
+// Result of merging an incoming history, lists file paths and group names
+type MergeReport struct {
+	Added     []string
+	Updated   []string
+	UpToDate  []string
+	Conflicts []string
+}
+
+// Reads the trackers of the repository stored in qweDir
+func LoadHistory(qweDir string) (History, error) {
+	h := History{
+		Files:  make(TrackerSchema),
+		Groups: make(GroupTrackerSchema),
+		Paths:  make(TrackFiles),
+	}
+	sources := []struct {
+		name     string
+		target   any
+		optional bool
+	}{
+		{trackerFile, &h.Files, false},
+		{groupTrackerFile, &h.Groups, false},
+		{FileName, &h.Paths, true},
+	}
+	for _, src := range sources {
+		path := filepath.Join(qweDir, src.name)
+		if _, err := os.Stat(path); err != nil {
+			if src.optional {
+				continue
+			}
+			return h, er.RepoNotFound
+		}
+		var content []byte
+		var err error
+		if qweDir == QweDir {
+			content, err = activeSession().read(src.name)
+		} else {
+			content, err = cp.ReadFile(path)
+		}
+		if err != nil {
+			return h, er.TrackerAccessErr
+		}
+		if err = json.Unmarshal(content, src.target); err != nil {
+			return h, er.TrackerParseErr
+		}
+	}
+	MigrateGroups(h.Groups, h.Files)
+	return h, nil
+}
+
+// Writes the history to the trackers of the repository in the working directory
+func (h History) Save() error {
+	content, err := json.MarshalIndent(h.Files, "", " ")
+	if err != nil {
+		return er.TrackerWriteErr
+	}
+	if err = SaveTracker(FileTrackerType, content); err != nil {
+		return err
+	}
+	content, err = json.MarshalIndent(h.Groups, "", " ")
+	if err != nil {
+		return er.TrackerWriteErr
+	}
+	if err = SaveTracker(GroupTrackerType, content); err != nil {
+		return err
+	}
+	return h.Paths.Save()
+}
+
+// Writes the history to the trackers of a repository outside the working directory, e.g. a remote.
+// Every tracker is replaced atomically, the reflog is not updated.
+func (h History) SaveTo(qweDir string) error {
+	targets := []struct {
+		name    string
+		content any
+	}{
+		{trackerFile, h.Files},
+		{groupTrackerFile, h.Groups},
+		{FileName, h.Paths},
+	}
+	for _, target := range targets {
+		content, err := json.MarshalIndent(target.content, "", " ")
+		if err != nil {
+			return er.TrackerWriteErr
+		}
+		if err = cp.WriteFile(filepath.Join(qweDir, target.name), content); err != nil {
+			return er.TrackerWriteErr
+		}
+	}
+	return nil
+}
+
+// Returns the part of the history that belongs to the targets, a target is a tracked file or else a group
+func (h History) SelectTargets(targets []string) (History, error) {
+	var fileIDs, groupIDs []string
+	for _, target := range targets {
+		id := utl.Hasher(target)
+		if _, ok := h.Files[id]; ok {
+			fileIDs = append(fileIDs, id)
+		} else if _, ok := h.Groups[id]; ok {
+			groupIDs = append(groupIDs, id)
+		} else {
+			return h, fmt.Errorf("%w: %s", er.FileNotTracked, target)
+		}
+	}
+	return h.Select(fileIDs, groupIDs), nil
+}
+
+// Returns the part of the history that belongs to the supplied file and group ids, files of the groups are included
+func (h History) Select(fileIDs, groupIDs []string) History {
+	selected := History{
+		Files:  make(TrackerSchema),
+		Groups: make(GroupTrackerSchema),
+		Paths:  make(TrackFiles),
+	}
+	addFile := func(fileID string) {
+		if val, ok := h.Files[fileID]; ok {
+			selected.Files[fileID] = val
+		}
+		if p, ok := h.Paths[fileID]; ok {
+			selected.Paths[fileID] = p
+		}
+	}
+	for _, fileID := range fileIDs {
+		addFile(fileID)
+	}
+	for _, groupID := range groupIDs {
+		gr, ok := h.Groups[groupID]
+		if !ok {
+			continue
+		}
+		selected.Groups[groupID] = gr
+		for _, fileID := range gr.FileIDs() {
+			addFile(fileID)
+		}
+	}
+	return selected
+}
+
+// Returns the ids of all objects the file histories are stored in
+func (h History) Objects() []string {
+	var objects []string
+	for _, val := range h.Files {
+		objects = append(objects, val.Objects()...)
+	}
+	sort.Strings(objects)
+	return objects
+}
+
+// Checks that an object id read from another repository or a bundle can not escape the object directory
+func ValidObjectID(objID string) bool {
+	return objID != "" && objID != "." && objID != ".." && !strings.ContainsAny(objID, "/\\")
+}
+
+// Returns the path of a file for reports
+func (h History) fileName(fileID string, incoming History) string {
+	if p, ok := incoming.Paths[fileID]; ok {
+		return p.FilePath
+	}
+	if p, ok := h.Paths[fileID]; ok {
+		return p.FilePath
+	}
+	return fileID
+}
+
+// Merges an incoming history into this one. Files and groups are added or fast-forwarded,
+// diverged histories are reported as conflicts and keep the local state.
+// A group is only merged if none of its files conflict.
+// Returns the objects of the incoming history the merged trackers refer to.
+func (h History) Merge(incoming History) (MergeReport, []string) {
+	var report MergeReport
+	var objects []string
+	conflicted := make(map[string]struct{})
+
+	fileIDs := make([]string, 0, len(incoming.Files))
+	for fileID := range incoming.Files {
+		fileIDs = append(fileIDs, fileID)
+	}
+	sort.Slice(fileIDs, func(i, j int) bool {
+		return h.fileName(fileIDs[i], incoming) < h.fileName(fileIDs[j], incoming)
+	})
+
+	for _, fileID := range fileIDs {
+		var local *Tracker
+		if val, ok := h.Files[fileID]; ok {
+			local = &val
+		}
+		merged, result := MergeFile(local, incoming.Files[fileID])
+		name := h.fileName(fileID, incoming)
+		switch result {
+		case MergeConflict:
+			conflicted[fileID] = struct{}{}
+			report.Conflicts = append(report.Conflicts, name)
+			continue
+		case MergeUpToDate:
+			report.UpToDate = append(report.UpToDate, name)
+			continue
+		case MergeAdded:
+			report.Added = append(report.Added, name)
+		case MergeFastForward:
+			report.Updated = append(report.Updated, name)
+		}
+		h.Files[fileID] = merged
+		objects = append(objects, merged.Objects()...)
+		if p, ok := incoming.Paths[fileID]; ok {
+			h.Paths[fileID] = p
+		}
+	}
+
+	groupIDs := make([]string, 0, len(incoming.Groups))
+	for groupID := range incoming.Groups {
+		groupIDs = append(groupIDs, groupID)
+	}
+	sort.Slice(groupIDs, func(i, j int) bool {
+		return incoming.Groups[groupIDs[i]].GroupName < incoming.Groups[groupIDs[j]].GroupName
+	})
+
+	for _, groupID := range groupIDs {
+		gr := incoming.Groups[groupID]
+		name := "group " + gr.GroupName
+		hasConflict := false
+		for _, fileID := range gr.FileIDs() {
+			if _, ok := conflicted[fileID]; ok {
+				hasConflict = true
+			}
+		}
+		var local *GroupTracker
+		if val, ok := h.Groups[groupID]; ok {
+			local = &val
+		}
+		merged, result := MergeGroup(local, gr)
+		if hasConflict && result != MergeUpToDate {
+			result = MergeConflict
+		}
+		switch result {
+		case MergeConflict:
+			report.Conflicts = append(report.Conflicts, name)
+			continue
+		case MergeUpToDate:
+			report.UpToDate = append(report.UpToDate, name)
+			continue
+		case MergeAdded:
+			report.Added = append(report.Added, name)
+		case MergeFastForward:
+			report.Updated = append(report.Updated, name)
+		}
+		h.Groups[groupID] = merged
+	}
+	return report, objects
+}
+
+// Prints the result of a merge
+func (r MergeReport) Print() {
+	for _, name := range r.Added {
+		fmt.Println("Added", name)
+	}
+	for _, name := range r.Updated {
+		fmt.Println("Updated", name)
+	}
+	for _, name := range r.Conflicts {
+		fmt.Println("Conflict:", name, "has diverged, kept the history of the destination")
+	}
+	fmt.Printf("%d added, %d updated, %d up to date, %d conflicts\n",
+		len(r.Added), len(r.Updated), len(r.UpToDate), len(r.Conflicts))
+}
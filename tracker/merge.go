@@ -0,0 +1,103 @@
+package tracker
+
+import "sort"
+
+// Outcomes of merging an incoming history into the local one
+const (
+	MergeUpToDate    = iota // Histories are equal or the local one is ahead
+	MergeAdded              // Only tracked in the incoming history
+	MergeFastForward        // The incoming history extends the local one
+	MergeConflict           // Histories diverged, the local one is kept
+)
+
+// Checks if every element of prefix starts the other list
+func isPrefix(prefix, list []string) bool {
+	if len(prefix) > len(list) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != list[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func versionUIDs(val Tracker) []string {
+	uids := make([]string, len(val.Versions))
+	for i, v := range val.Versions {
+		uids[i] = v.UID
+	}
+	return uids
+}
+
+// Merges the incoming history of a file into the local one, local is nil if the file is not tracked locally.
+// The checked out version of a local file is never changed.
+func MergeFile(local *Tracker, incoming Tracker) (Tracker, int) {
+	if local == nil {
+		return incoming, MergeAdded
+	}
+	if local.Base != incoming.Base {
+		return *local, MergeConflict
+	}
+
+	localUIDs := versionUIDs(*local)
+	incomingUIDs := versionUIDs(incoming)
+	switch {
+	case isPrefix(incomingUIDs, localUIDs):
+		return *local, MergeUpToDate
+	case isPrefix(localUIDs, incomingUIDs):
+		merged := *local
+		merged.Versions = append([]VersionDetails(nil), incoming.Versions...)
+		return merged, MergeFastForward
+	}
+	return *local, MergeConflict
+}
+
+// Merges the incoming history of a group into the local one, local is nil if the group does not exist locally.
+// The checked out version of a local group is never changed.
+func MergeGroup(local *GroupTracker, incoming GroupTracker) (GroupTracker, int) {
+	if local == nil {
+		return incoming, MergeAdded
+	}
+
+	switch {
+	case isPrefix(incoming.VersionOrder, local.VersionOrder):
+		return *local, MergeUpToDate
+	case isPrefix(local.VersionOrder, incoming.VersionOrder):
+		merged := *local
+		merged.VersionOrder = append([]string(nil), incoming.VersionOrder...)
+		merged.Versions = make(map[string]GroupVersionDetails, len(incoming.Versions))
+		for k, v := range incoming.Versions {
+			merged.Versions[k] = v
+		}
+		return merged, MergeFastForward
+	}
+	return *local, MergeConflict
+}
+
+// Returns the ids of the objects the history of a file is stored in
+func (tr *Tracker) Objects() []string {
+	objects := []string{tr.Base}
+	for _, v := range tr.Versions {
+		objects = append(objects, v.UID)
+	}
+	return objects
+}
+
+// Returns the ids of the files that are part of any version of the group
+func (gr *GroupTracker) FileIDs() []string {
+	seen := make(map[string]struct{})
+	var ids []string
+	for _, k := range gr.VersionOrder {
+		for fileID := range gr.Versions[k].Files {
+			if _, ok := seen[fileID]; ok {
+				continue
+			}
+			seen[fileID] = struct{}{}
+			ids = append(ids, fileID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,68 @@
+package tracker
+
+import (
+	"encoding/json"
+
+	er "github.com/mainak55512/qwe/qwerror"
+)
+
+// Checks if any group version still refers to file commits by their position
+func needsMigration(groups GroupTrackerSchema) bool {
+	for _, gr := range groups {
+		for _, version := range gr.Versions {
+			for _, f := range version.Files {
+				if f.CommitNumber != nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Converts group file references written by earlier versions from commit numbers to commit UIDs.
+// Commit number -2 refers to the base version, -3 to a failed commit that is kept without UID.
+// Returns true if any reference was converted.
+func MigrateGroups(groups GroupTrackerSchema, files TrackerSchema) bool {
+	migrated := false
+	for groupID, gr := range groups {
+		for versionID, version := range gr.Versions {
+			for fileID, f := range version.Files {
+				if f.CommitNumber == nil {
+					continue
+				}
+				uid := f.FileObjID
+				if val, ok := files[fileID]; ok {
+					switch n := *f.CommitNumber; {
+					case n == -2:
+						uid = val.Base
+					case n >= 0 && n < len(val.Versions):
+						uid = val.Versions[n].UID
+					}
+				}
+				version.Files[fileID] = FileDetails{FileName: f.FileName, UID: uid}
+				migrated = true
+			}
+			gr.Versions[versionID] = version
+		}
+		groups[groupID] = gr
+	}
+	return migrated
+}
+
+// Migrates the group tracker of the working directory. It is saved like every other tracker change,
+// the reflog records no entry as no version moves.
+func migrateGroupTracker(groups GroupTrackerSchema) error {
+	files, _, err := GetTracker(FileTrackerType)
+	if err != nil {
+		return err
+	}
+	if !MigrateGroups(groups, files) {
+		return nil
+	}
+	content, err := json.MarshalIndent(groups, "", " ")
+	if err != nil {
+		return er.TrackerWriteErr
+	}
+	return SaveTracker(GroupTrackerType, content)
+}
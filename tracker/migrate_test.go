@@ -0,0 +1,78 @@
+package tracker
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	cp "github.com/mainak55512/qwe/compressor"
+)
+
+func TestMigrateGroupTracker(t *testing.T) {
+	_, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	files := TrackerSchema{
+		"file": {
+			Base:    "_base_a",
+			Current: "c2",
+			Versions: []VersionDetails{
+				{UID: "c1"},
+				{UID: "c2"},
+			},
+		},
+	}
+	content, err := json.Marshal(files)
+	if err != nil {
+		t.Fatalf("failed to marshal tracker: %v", err)
+	}
+	if err := SaveTracker(FileTrackerType, content); err != nil {
+		t.Fatalf("failed to save tracker: %v", err)
+	}
+
+	// Group tracker as written before commits were referenced by UID
+	legacy := `{"group": {"group_name": "docs", "current": "v3", "version_order": ["v1", "v2", "v3"], "versions": {
+		"v1": {"commit_message": "", "files": {"file": {"file_name": "a.txt", "commit_number": -2, "file_obj_id": "_base_a"}}},
+		"v2": {"commit_message": "", "files": {"file": {"file_name": "a.txt", "commit_number": 1, "file_obj_id": ""}}},
+		"v3": {"commit_message": "", "files": {"file": {"file_name": "a.txt", "commit_number": -3, "file_obj_id": ""}}}
+	}}}`
+	if err := SaveTracker(GroupTrackerType, []byte(legacy)); err != nil {
+		t.Fatalf("failed to save group tracker: %v", err)
+	}
+
+	before, err := loadReflog()
+	if err != nil {
+		t.Fatalf("failed to read reflog: %v", err)
+	}
+
+	_, groups, err := GetTracker(GroupTrackerType)
+	if err != nil {
+		t.Fatalf("GetTracker() failed: %v", err)
+	}
+	want := map[string]string{"v1": "_base_a", "v2": "c2", "v3": ""}
+	for versionID, uid := range want {
+		f := groups["group"].Versions[versionID].Files["file"]
+		if f.UID != uid || f.CommitNumber != nil || f.FileObjID != "" {
+			t.Errorf("version %s: expected UID %q without legacy fields, got %+v", versionID, uid, f)
+		}
+	}
+
+	// The migrated tracker is written back
+	raw, err := cp.ReadFile(".qwe/_group_tracker.qwe")
+	if err != nil {
+		t.Fatalf("failed to read group tracker: %v", err)
+	}
+	if strings.Contains(string(raw), "commit_number") {
+		t.Errorf("expected the group tracker on disk to be migrated, got %s", raw)
+	}
+
+	// Nothing moved for the user, so the migration adds no reflog changes
+	after, err := loadReflog()
+	if err != nil {
+		t.Fatalf("failed to read reflog: %v", err)
+	}
+	if len(after) != len(before) || len(after) > 0 && !reflect.DeepEqual(after[len(after)-1].Changes, before[len(before)-1].Changes) {
+		t.Errorf("expected the reflog to be unchanged by the migration, got %+v instead of %+v", after, before)
+	}
+}
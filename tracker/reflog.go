@@ -0,0 +1,584 @@
+package tracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+
+	tw "text/tabwriter"
+
+	bh "github.com/mainak55512/qwe/binaryhandler"
+	cp "github.com/mainak55512/qwe/compressor"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+)
+
+const (
+	ReflogFile = "_reflog.qwe"
+
+	trackerFile      = "_tracker.qwe"
+	groupTrackerFile = "_group_tracker.qwe"
+	undoCommand      = "undo"
+)
+
+// Number of reflog entries that are kept, older entries and their tracker snapshots are deleted
+var ReflogLimit = 100
+
+// Movement of the current pointer of a file or group, an empty pointer means the entry did not exist
+type PointerChange struct {
+	IsGroup bool   `json:"is_group"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+}
+
+// A reflog entry is recorded for every command that changes a tracker, Changes lists the moved current pointers
+// and the added or removed entries.
+// Snapshots hold the object ids of the tracker files as they were before the command.
+type ReflogEntry struct {
+	ID        string            `json:"id"`
+	Command   string            `json:"command"`
+	TimeStamp string            `json:"time_stamp"`
+	Changes   []PointerChange   `json:"changes"`
+	Snapshots map[string]string `json:"snapshots"`
+	Undone    bool              `json:"undone"`
+}
+
+type ReflogSchema []ReflogEntry
+
+// Changes made by the running command, all of them are recorded in a single reflog entry
+type journal struct {
+	command   string
+	root      string
+	entryID   string
+	preImages map[string][]byte
+	snapshots map[string]string
+}
+
+var currentJournal = newJournal("qwe")
+
+func newJournal(command string) *journal {
+	root, _ := os.Getwd()
+	return &journal{
+		command:   command,
+		root:      root,
+		preImages: map[string][]byte{},
+		snapshots: map[string]string{},
+	}
+}
+
+// Sets the command recorded in the reflog for the changes made afterwards
+func SetReflogCommand(command string) {
+	currentJournal = newJournal(command)
+}
+
+// Starts a new journal if the working directory moved to another repository
+func activeJournal() *journal {
+	if root, _ := os.Getwd(); root != currentJournal.root {
+		currentJournal = newJournal(currentJournal.command)
+	}
+	return currentJournal
+}
+
+// Keeps the compressed content of a tracker file as it was before the running command first wrote it
+func capturePreImage(path string) {
+	name := filepath.Base(path)
+	if _, ok := activeJournal().preImages[name]; ok {
+		return
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		content = nil
+	}
+	currentJournal.preImages[name] = content
+}
+
+// Decompresses the content of a tracker file captured before the running command
+func preImage(name string) []byte {
+	raw := currentJournal.preImages[name]
+	if len(raw) == 0 {
+		return nil
+	}
+	content, err := cp.DecompressBytes(raw)
+	if err != nil {
+		return nil
+	}
+	return content
+}
+
+// Returns the names and current pointers of all the files or groups of a tracker file content
+func pointers(name string, content []byte) (map[string]string, map[string]string) {
+	names := make(map[string]string)
+	current := make(map[string]string)
+	if len(content) == 0 {
+		return names, current
+	}
+	if name == trackerFile {
+		var schema TrackerSchema
+		if json.Unmarshal(content, &schema) == nil {
+			for k, v := range schema {
+				current[k] = v.Current
+			}
+		}
+	} else {
+		var schema GroupTrackerSchema
+		if json.Unmarshal(content, &schema) == nil {
+			for k, v := range schema {
+				names[k] = v.GroupName
+				current[k] = v.Current
+			}
+		}
+	}
+	return names, current
+}
+
+// Returns the paths of tracked files, including the files removed by the running command
+func trackedNames() map[string]string {
+	names := make(map[string]string)
+	var before, after TrackFiles
+	if json.Unmarshal(preImage(FileName), &before) == nil {
+		for k, v := range before {
+			names[k] = v.FilePath
+		}
+	}
+	if content, err := activeSession().read(FileName); err == nil {
+		if json.Unmarshal(content, &after) == nil {
+			for k, v := range after {
+				names[k] = v.FilePath
+			}
+		}
+	}
+	return names
+}
+
+// Reports whether the running command changed the content of a tracker, e.g. rewrote a history without moving
+// its current pointer. Group references written by earlier versions are migrated first, migrating is not a change.
+func rewritten() bool {
+	files := preImage(trackerFile)
+	if files == nil {
+		files, _ = activeSession().read(trackerFile)
+	}
+	for _, name := range []string{trackerFile, groupTrackerFile} {
+		// A created tracker only adds entries, they are recorded as pointer changes
+		if preImage(name) == nil {
+			continue
+		}
+		latest, err := activeSession().read(name)
+		if err != nil {
+			continue
+		}
+		if name == trackerFile {
+			var before, after TrackerSchema
+			json.Unmarshal(preImage(name), &before)
+			json.Unmarshal(latest, &after)
+			if !reflect.DeepEqual(before, after) {
+				return true
+			}
+			continue
+		}
+		var before, after GroupTrackerSchema
+		var fileTracker TrackerSchema
+		json.Unmarshal(preImage(name), &before)
+		json.Unmarshal(latest, &after)
+		json.Unmarshal(files, &fileTracker)
+		MigrateGroups(before, fileTracker)
+		if !reflect.DeepEqual(before, after) {
+			return true
+		}
+	}
+	return false
+}
+
+// Updates the reflog entry of the running command after a tracker file is written
+func recordSave() error {
+	activeJournal()
+	var changes []PointerChange
+	fileNames := trackedNames()
+
+	for _, name := range []string{trackerFile, groupTrackerFile} {
+		if _, ok := currentJournal.preImages[name]; !ok {
+			continue
+		}
+		latest, err := activeSession().read(name)
+		if err != nil {
+			continue
+		}
+		oldNames, before := pointers(name, preImage(name))
+		newNames, after := pointers(name, latest)
+
+		ids := make(map[string]struct{})
+		for k := range before {
+			ids[k] = struct{}{}
+		}
+		for k := range after {
+			ids[k] = struct{}{}
+		}
+		for id := range ids {
+			if before[id] == after[id] {
+				continue
+			}
+			change := PointerChange{
+				IsGroup: name == groupTrackerFile,
+				ID:      id,
+				Before:  before[id],
+				After:   after[id],
+			}
+			if change.IsGroup {
+				change.Name = newNames[id]
+				if change.Name == "" {
+					change.Name = oldNames[id]
+				}
+			} else {
+				change.Name = fileNames[id]
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	// Commands that leave the trackers as they were are not recorded
+	if len(changes) == 0 && !rewritten() && currentJournal.entryID == "" {
+		return nil
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].IsGroup != changes[j].IsGroup {
+			return !changes[i].IsGroup
+		}
+		return changes[i].Name < changes[j].Name
+	})
+
+	// Store the tracker files as they were before the command, so the command can be undone
+	for name, raw := range currentJournal.preImages {
+		if _, ok := currentJournal.snapshots[name]; ok || len(raw) == 0 {
+			continue
+		}
+		content := preImage(name)
+		if content == nil {
+			continue
+		}
+		objID := "_reflog_" + utl.Hasher(fmt.Sprintf("%s%s%d", currentJournal.command, name, time.Now().UnixNano()))
+		if err := cp.WriteFile(filepath.Join(QweDir, "_object", objID), content); err != nil {
+			return err
+		}
+		currentJournal.snapshots[name] = objID
+	}
+
+	reflog, err := loadReflog()
+	if err != nil {
+		return err
+	}
+	idx := -1
+	for i := range reflog {
+		if currentJournal.entryID != "" && reflog[i].ID == currentJournal.entryID {
+			idx = i
+		}
+	}
+	if idx == -1 {
+		currentJournal.entryID = utl.Hasher(fmt.Sprintf("%s%d", currentJournal.command, time.Now().UnixNano()))
+		reflog = append(reflog, ReflogEntry{
+			ID:        currentJournal.entryID,
+			Command:   currentJournal.command,
+			TimeStamp: time.Now().String()[:16],
+		})
+		idx = len(reflog) - 1
+	}
+	reflog[idx].Changes = changes
+	reflog[idx].Snapshots = currentJournal.snapshots
+
+	reflog, expired := trimReflog(reflog)
+	if err = saveReflog(reflog); err != nil {
+		return err
+	}
+	removeSnapshots(reflog, expired)
+	return nil
+}
+
+// Splits off the entries beyond ReflogLimit, oldest first. The entry of the running command is always kept.
+func trimReflog(reflog ReflogSchema) (ReflogSchema, ReflogSchema) {
+	if ReflogLimit <= 0 || len(reflog) <= ReflogLimit {
+		return reflog, nil
+	}
+	cut := len(reflog) - ReflogLimit
+	return append(ReflogSchema{}, reflog[cut:]...), reflog[:cut]
+}
+
+// Deletes the tracker snapshots of expired reflog entries, they can no longer be undone. Objects that only the
+// expired snapshots referred to, e.g. the commits replaced by a squash, are deleted with them.
+func removeSnapshots(reflog, expired ReflogSchema) {
+	candidates := make(map[string]bool)
+	for _, e := range expired {
+		for name, objID := range e.Snapshots {
+			content, err := cp.ReadFile(filepath.Join(QweDir, "_object", objID))
+			if err == nil {
+				for _, id := range trackerObjects(name, content) {
+					candidates[id] = true
+				}
+			}
+			os.Remove(filepath.Join(QweDir, "_object", objID))
+		}
+	}
+	for _, name := range []string{trackerFile, groupTrackerFile} {
+		content, err := activeSession().read(name)
+		if err != nil {
+			return
+		}
+		for _, id := range trackerObjects(name, content) {
+			delete(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	referenced, err := snapshotObjects(reflog)
+	if err != nil {
+		return
+	}
+	for _, name := range []string{trackerFile, groupTrackerFile} {
+		content, _ := activeSession().read(name)
+		referenced = append(referenced, trackerObjects(name, content)...)
+	}
+	kept, err := bh.WithDependencies(filepath.Join(QweDir, "_object"), referenced)
+	if err != nil {
+		return
+	}
+	for _, id := range kept {
+		delete(candidates, id)
+	}
+	for id := range candidates {
+		os.Remove(filepath.Join(QweDir, "_object", id))
+	}
+}
+
+// Returns the objects the tracker snapshots of the reflog refer to, undo may restore any of them
+func SnapshotObjects() ([]string, error) {
+	reflog, err := loadReflog()
+	if err != nil {
+		return nil, err
+	}
+	return snapshotObjects(reflog)
+}
+
+func snapshotObjects(reflog ReflogSchema) ([]string, error) {
+	var objects []string
+	for _, e := range reflog {
+		for name, objID := range e.Snapshots {
+			content, err := cp.ReadFile(filepath.Join(QweDir, "_object", objID))
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, trackerObjects(name, content)...)
+		}
+	}
+	return objects, nil
+}
+
+// Returns the objects a file or group tracker refers to
+func trackerObjects(name string, content []byte) []string {
+	var objects []string
+	switch name {
+	case trackerFile:
+		var tracker TrackerSchema
+		json.Unmarshal(content, &tracker)
+		for _, val := range tracker {
+			objects = append(objects, val.Base, val.Current)
+			for _, version := range val.Versions {
+				objects = append(objects, version.UID)
+			}
+		}
+	case groupTrackerFile:
+		var groupTracker GroupTrackerSchema
+		json.Unmarshal(content, &groupTracker)
+		for _, gr := range groupTracker {
+			for _, version := range gr.Versions {
+				for _, f := range version.Files {
+					objects = append(objects, f.UID)
+				}
+			}
+		}
+	}
+	return slices.DeleteFunc(objects, func(objID string) bool { return objID == "" })
+}
+
+// Reads the reflog from _reflog.qwe, repositories without a reflog have an empty one
+func loadReflog() (ReflogSchema, error) {
+	var reflog ReflogSchema
+	path := filepath.Join(QweDir, ReflogFile)
+	if !utl.FileExists(path) {
+		return reflog, nil
+	}
+	content, err := cp.ReadFile(path)
+	if err != nil {
+		return nil, er.TrackerAccessErr
+	}
+	if err = json.Unmarshal(content, &reflog); err != nil {
+		return nil, er.TrackerParseErr
+	}
+	return reflog, nil
+}
+
+// Updates _reflog.qwe file
+func saveReflog(reflog ReflogSchema) error {
+	content, err := json.MarshalIndent(reflog, "", " ")
+	if err != nil {
+		return er.TrackerWriteErr
+	}
+	if err = cp.WriteFile(filepath.Join(QweDir, ReflogFile), content); err != nil {
+		return er.TrackerWriteErr
+	}
+	return nil
+}
+
+// Converts a pointer to the commit number shown to the user where possible
+func describePointer(change PointerChange, pointer string, tracker TrackerSchema, groupTracker GroupTrackerSchema) string {
+	if pointer == "" {
+		return "none"
+	}
+	if change.IsGroup {
+		if val, ok := groupTracker[change.ID]; ok {
+			for i, v := range val.VersionOrder {
+				if v == pointer {
+					return fmt.Sprintf("%d", i)
+				}
+			}
+		}
+	} else if val, ok := tracker[change.ID]; ok {
+		if pointer == val.Base {
+			return "base"
+		}
+		for i, v := range val.Versions {
+			if v.UID == pointer {
+				return fmt.Sprintf("%d", i)
+			}
+		}
+	}
+	return pointer
+}
+
+// Prints the reflog, latest entry first
+func PrintReflog() error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+
+	reflog, err := loadReflog()
+	if err != nil {
+		return err
+	}
+	if len(reflog) == 0 {
+		fmt.Println("Reflog is empty!")
+		return nil
+	}
+
+	tracker, _, err := GetTracker(FileTrackerType)
+	if err != nil {
+		return err
+	}
+	_, groupTracker, err := GetTracker(GroupTrackerType)
+	if err != nil {
+		return err
+	}
+
+	w := new(tw.Writer)
+	w.Init(os.Stdout, 0, 0, 0, ' ', tw.TabIndent)
+	for i := len(reflog) - 1; i >= 0; i-- {
+		e := reflog[i]
+		command := e.Command
+		if e.Undone {
+			command += " (undone)"
+		}
+		fmt.Fprintf(w, "\nID:\t%d\nCommand:\t%s\nTime Stamp:\t%s\n", len(reflog)-1-i, command, e.TimeStamp)
+		for _, c := range e.Changes {
+			kind := "File:"
+			if c.IsGroup {
+				kind = "Group:"
+			}
+			name := c.Name
+			if name == "" {
+				name = c.ID
+			}
+			fmt.Fprintf(w, "%s\t%s %s -> %s\n", kind, name,
+				describePointer(c, c.Before, tracker, groupTracker),
+				describePointer(c, c.After, tracker, groupTracker))
+		}
+	}
+	w.Flush()
+	return nil
+}
+
+// Restores the tracker state from before the latest command that is not undone yet.
+// Working files are left untouched.
+func Undo() error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+
+	reflog, err := loadReflog()
+	if err != nil {
+		return err
+	}
+
+	idx := -1
+	for i := len(reflog) - 1; i >= 0; i-- {
+		if !reflog[i].Undone && reflog[i].Command != undoCommand {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return er.NothingToUndo
+	}
+	entry := reflog[idx]
+
+	SetReflogCommand(undoCommand)
+	for _, name := range []string{trackerFile, groupTrackerFile, FileName} {
+		objID, ok := entry.Snapshots[name]
+		if !ok {
+			continue
+		}
+		content, err := cp.ReadFile(filepath.Join(QweDir, "_object", objID))
+		if err != nil {
+			return err
+		}
+		switch name {
+		case trackerFile:
+			err = SaveTracker(FileTrackerType, content)
+		case groupTrackerFile:
+			err = SaveTracker(GroupTrackerType, content)
+		default:
+			trackedFiles := make(TrackFiles)
+			if err = json.Unmarshal(content, &trackedFiles); err == nil {
+				err = trackedFiles.Save()
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	reflog, err = loadReflog()
+	if err != nil {
+		return err
+	}
+	for i := range reflog {
+		if reflog[i].ID == entry.ID {
+			reflog[i].Undone = true
+		}
+	}
+	if err = saveReflog(reflog); err != nil {
+		return err
+	}
+
+	fmt.Println("Restored tracker state from before", "'"+entry.Command+"'")
+	for _, c := range entry.Changes {
+		if !c.IsGroup && c.Before != "" && c.Name != "" {
+			fmt.Println("Working file", c.Name, "is unchanged, use 'revert' to update it if needed")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package tracker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cp "github.com/mainak55512/qwe/compressor"
+)
+
+func TestReflogRetention(t *testing.T) {
+	_, cleanup := setupTestDir(t)
+	defer cleanup()
+	defer func(limit int) { ReflogLimit = limit }(ReflogLimit)
+	ReflogLimit = 2
+
+	if err := os.MkdirAll(filepath.Join(QweDir, "_object"), 0o755); err != nil {
+		t.Fatalf("failed to create object directory: %v", err)
+	}
+	if err := SaveTracker(FileTrackerType, []byte("{}")); err != nil {
+		t.Fatalf("failed to initialize file tracker: %v", err)
+	}
+	if err := SaveTracker(GroupTrackerType, []byte("{}")); err != nil {
+		t.Fatalf("failed to initialize group tracker: %v", err)
+	}
+	if err := InitTrackedFiles(); err != nil {
+		t.Fatalf("failed to initialize tracked files: %v", err)
+	}
+
+	for _, filePath := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		if err := os.WriteFile(filePath, []byte("content\n"), 0o644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+		SetReflogCommand("track " + filePath)
+		if _, err := StartTracking(filePath); err != nil {
+			t.Fatalf("StartTracking() failed: %v", err)
+		}
+	}
+
+	reflog, err := loadReflog()
+	if err != nil {
+		t.Fatalf("failed to read reflog: %v", err)
+	}
+	if len(reflog) != 2 || reflog[0].Command != "track c.txt" || reflog[1].Command != "track d.txt" {
+		t.Fatalf("expected the two latest entries to be kept, got %+v", reflog)
+	}
+
+	// Only the snapshots of the kept entries are left, they are compressed like every other object
+	kept := make(map[string]bool)
+	for _, e := range reflog {
+		for _, objID := range e.Snapshots {
+			kept[objID] = true
+			if _, err := cp.ReadFile(filepath.Join(QweDir, "_object", objID)); err != nil {
+				t.Errorf("snapshot %s is not readable: %v", objID, err)
+			}
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Join(QweDir, "_object"))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "_reflog_") && !kept[entry.Name()] {
+			t.Errorf("snapshot %s of an expired entry was not deleted", entry.Name())
+		}
+	}
+
+	if err := Undo(); err != nil {
+		t.Fatalf("Undo() failed: %v", err)
+	}
+	tracker, _, err := GetTracker(FileTrackerType)
+	if err != nil || len(tracker) != 3 {
+		t.Errorf("expected undo to restore three tracked files, got %d, %v", len(tracker), err)
+	}
+}
+
+func TestReflogRecordsRewrites(t *testing.T) {
+	_, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	if err := os.MkdirAll(filepath.Join(QweDir, "_object"), 0o755); err != nil {
+		t.Fatalf("failed to create object directory: %v", err)
+	}
+	if err := SaveTracker(FileTrackerType, []byte(`{"a":{"base":"b","current":"v2","versions":[{"uid":"v1"},{"uid":"v2"}]}}`)); err != nil {
+		t.Fatalf("failed to initialize file tracker: %v", err)
+	}
+	if err := SaveTracker(GroupTrackerType, []byte("{}")); err != nil {
+		t.Fatalf("failed to initialize group tracker: %v", err)
+	}
+	if err := InitTrackedFiles(); err != nil {
+		t.Fatalf("failed to initialize tracked files: %v", err)
+	}
+
+	// Rewriting the versions keeps the current pointer, the command is recorded all the same
+	SetReflogCommand("squash")
+	if err := SaveTracker(FileTrackerType, []byte(`{"a":{"base":"b","current":"v2","versions":[{"uid":"v2"}]}}`)); err != nil {
+		t.Fatalf("failed to rewrite file tracker: %v", err)
+	}
+	reflog, err := loadReflog()
+	if err != nil {
+		t.Fatalf("failed to read reflog: %v", err)
+	}
+	if len(reflog) == 0 || reflog[len(reflog)-1].Command != "squash" {
+		t.Fatalf("expected the rewrite to be recorded, got %+v", reflog)
+	}
+
+	if err := Undo(); err != nil {
+		t.Fatalf("Undo() failed: %v", err)
+	}
+	tracker, _, err := GetTracker(FileTrackerType)
+	if err != nil || len(tracker["a"].Versions) != 2 {
+		t.Errorf("expected undo to restore both versions, got %+v, %v", tracker["a"], err)
+	}
+}
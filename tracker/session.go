@@ -0,0 +1,143 @@
+package tracker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	cp "github.com/mainak55512/qwe/compressor"
+	er "github.com/mainak55512/qwe/qwerror"
+)
+
+// Tracker files of the repository in the working directory, kept in memory for the running command.
+// A file is decompressed once and read again only if it changed on disk. Writes go to disk right away
+// unless DeferWrites was called, then they are kept until FlushSession.
+type session struct {
+	root     string
+	deferred bool
+	files    map[string]*sessionFile
+}
+
+type sessionFile struct {
+	content []byte // decompressed content, shared by all readers and never modified
+	info    os.FileInfo
+	dirty   bool
+}
+
+var currentSession = newSession(false)
+
+func newSession(deferred bool) *session {
+	root, _ := os.Getwd()
+	return &session{
+		root:     root,
+		deferred: deferred,
+		files:    map[string]*sessionFile{},
+	}
+}
+
+// Starts a new session if the working directory moved to another repository
+func activeSession() *session {
+	if root, _ := os.Getwd(); root != currentSession.root {
+		currentSession = newSession(currentSession.deferred)
+	}
+	return currentSession
+}
+
+// Keeps the tracker files written by the running command in memory, they are written once by FlushSession
+func DeferWrites() {
+	activeSession().deferred = true
+}
+
+// Writes the tracker files changed since DeferWrites and records them in a single reflog entry
+func FlushSession() error {
+	s := activeSession()
+	s.deferred = false
+	return s.flush()
+}
+
+// Writes the tracker files changed so far while later writes stay deferred. Commands that must not report success
+// before their changes are on disk call it, a command that already wrote everything does not write again.
+func SyncSession() error {
+	return activeSession().flush()
+}
+
+// Puts back the content both trackers had before a failed operation and writes them right away
+func RestoreTrackers(fileTracker, groupTracker []byte) error {
+	s := activeSession()
+	s.files[trackerFile] = &sessionFile{content: fileTracker, dirty: true}
+	s.files[groupTrackerFile] = &sessionFile{content: groupTracker, dirty: true}
+	return s.flush()
+}
+
+// Returns the decompressed content of a tracker file, name is the file name inside .qwe
+func (s *session) read(name string) ([]byte, error) {
+	f, ok := s.files[name]
+	if ok && f.dirty {
+		return f.content, nil
+	}
+	path := filepath.Join(QweDir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if ok && unchanged(f.info, info) {
+		return f.content, nil
+	}
+	content, err := cp.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s.files[name] = &sessionFile{content: content, info: info}
+	return content, nil
+}
+
+// Reports whether a file on disk is still the one that was read or written
+func unchanged(old, info os.FileInfo) bool {
+	return old != nil && os.SameFile(old, info) && old.Size() == info.Size() && old.ModTime().Equal(info.ModTime())
+}
+
+// Replaces the content of a tracker file, it is written to disk right away unless writes are deferred
+func (s *session) write(name string, content []byte) error {
+	s.files[name] = &sessionFile{content: content, dirty: true}
+	if s.deferred {
+		return nil
+	}
+	return s.flush()
+}
+
+// Writes the changed tracker files and updates the reflog entry of the running command
+func (s *session) flush() error {
+	written := false
+	for _, name := range []string{trackerFile, groupTrackerFile, FileName} {
+		f, ok := s.files[name]
+		if !ok || !f.dirty {
+			continue
+		}
+		info, err := writeCompressed(name, f.content)
+		if err != nil {
+			return err
+		}
+		f.info, f.dirty = info, false
+		written = true
+	}
+	if !written {
+		return nil
+	}
+	if err := recordSave(); err != nil {
+		fmt.Printf("Warning: failed to update reflog: %v\n", err)
+	}
+	return nil
+}
+
+// Replaces a tracker file with the compressed content, readers never see a partial file
+func writeCompressed(name string, content []byte) (os.FileInfo, error) {
+	path := filepath.Join(QweDir, name)
+
+	// Keep the previous state of the tracker for the reflog
+	capturePreImage(path)
+
+	if err := cp.WriteFile(path, content); err != nil {
+		return nil, er.TrackerWriteErr
+	}
+	return os.Stat(path)
+}
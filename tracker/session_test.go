@@ -0,0 +1,135 @@
+package tracker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cp "github.com/mainak55512/qwe/compressor"
+)
+
+func TestSessionDefersWrites(t *testing.T) {
+	_, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	if err := os.MkdirAll(filepath.Join(QweDir, "_object"), 0o755); err != nil {
+		t.Fatalf("failed to create object directory: %v", err)
+	}
+	if err := SaveTracker(FileTrackerType, []byte("{}")); err != nil {
+		t.Fatalf("failed to initialize file tracker: %v", err)
+	}
+	if err := SaveTracker(GroupTrackerType, []byte("{}")); err != nil {
+		t.Fatalf("failed to initialize group tracker: %v", err)
+	}
+	if err := InitTrackedFiles(); err != nil {
+		t.Fatalf("failed to initialize tracked files: %v", err)
+	}
+	onDisk := func(name string) string {
+		t.Helper()
+		content, err := cp.ReadFile(filepath.Join(QweDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		return string(content)
+	}
+
+	SetReflogCommand("bulk")
+	DeferWrites()
+	for _, filePath := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filePath, []byte("content\n"), 0o644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+		if _, err := StartTracking(filePath); err != nil {
+			t.Fatalf("StartTracking() failed: %v", err)
+		}
+	}
+
+	// Reads see the changes of the command, the repository does not until they are flushed
+	tracker, _, err := GetTracker(FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	if len(tracker) != 2 {
+		t.Errorf("expected two tracked files in the session, got %d", len(tracker))
+	}
+	if got := onDisk(trackerFile); got != "{}" {
+		t.Errorf("expected the tracker on disk to be unchanged before the flush, got %s", got)
+	}
+
+	if err := FlushSession(); err != nil {
+		t.Fatalf("FlushSession() failed: %v", err)
+	}
+	if got := onDisk(trackerFile); got == "{}" {
+		t.Error("expected the tracker on disk to be written by the flush")
+	}
+	reflog, err := loadReflog()
+	if err != nil {
+		t.Fatalf("failed to read reflog: %v", err)
+	}
+	if len(reflog) != 1 || len(reflog[0].Changes) != 2 {
+		t.Fatalf("expected a single reflog entry with two changes, got %+v", reflog)
+	}
+
+	// A tracker written outside the session is read again
+	path := filepath.Join(QweDir, trackerFile)
+	if err := os.WriteFile(path, []byte("{}"), TrackFilePermissions); err != nil {
+		t.Fatalf("failed to write tracker: %v", err)
+	}
+	if err := cp.CompressFile(path); err != nil {
+		t.Fatalf("failed to compress tracker: %v", err)
+	}
+	if tracker, _, err = GetTracker(FileTrackerType); err != nil || len(tracker) != 0 {
+		t.Errorf("expected the tracker written outside the session, got %v, %v", tracker, err)
+	}
+}
+
+// Tracks files one command at a time, with deferred writes the trackers are written once instead of once per file
+func BenchmarkSessionWrites(b *testing.B) {
+	for _, deferred := range []bool{false, true} {
+		name := "immediate"
+		if deferred {
+			name = "deferred"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.Chdir(b.TempDir())
+			for i := range b.N {
+				b.StopTimer()
+				dir := fmt.Sprint(i)
+				if err := os.MkdirAll(filepath.Join(dir, QweDir, "_object"), 0o755); err != nil {
+					b.Fatalf("failed to create repository: %v", err)
+				}
+				if err := os.Chdir(dir); err != nil {
+					b.Fatalf("failed to enter repository: %v", err)
+				}
+				if err := SaveTracker(FileTrackerType, []byte("{}")); err != nil {
+					b.Fatalf("failed to initialize file tracker: %v", err)
+				}
+				if err := InitTrackedFiles(); err != nil {
+					b.Fatalf("failed to initialize tracked files: %v", err)
+				}
+				for j := range 50 {
+					if err := os.WriteFile(fmt.Sprintf("f%d.txt", j), []byte("content\n"), 0o644); err != nil {
+						b.Fatalf("failed to create file: %v", err)
+					}
+				}
+				b.StartTimer()
+
+				if deferred {
+					DeferWrites()
+				}
+				for j := range 50 {
+					if _, err := StartTracking(fmt.Sprintf("f%d.txt", j)); err != nil {
+						b.Fatalf("StartTracking() failed: %v", err)
+					}
+				}
+				if err := FlushSession(); err != nil {
+					b.Fatalf("FlushSession() failed: %v", err)
+				}
+				if err := os.Chdir(".."); err != nil {
+					b.Fatalf("failed to leave repository: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -147,20 +147,9 @@ func (tf TrackFiles) Save() error {
 		return fmt.Errorf("marshal tracked files: %w", err)
 	}
 
-	// atomic write
-	filePath := filepath.Join(QweDir, FileName)
-	tmp := filePath + ".tmp"
-
-	if err := os.WriteFile(tmp, bytes, TrackFilePermissions); err != nil {
-		return fmt.Errorf("write temp file: %w", err)
-	}
-
-	if err := os.Rename(tmp, filePath); err != nil {
-		return fmt.Errorf("rename temp file: %w", err)
-	}
-
-	if err = cp.CompressFile(filePath); err != nil {
-		return fmt.Errorf("compress file: %w", err)
+	// Written atomically together with the trackers of the running command
+	if err := activeSession().write(FileName, bytes); err != nil {
+		return fmt.Errorf("save tracked files: %w", err)
 	}
 	return nil
 }
@@ -168,14 +157,10 @@ func (tf TrackFiles) Save() error {
 func InitTrackedFiles() error {
 	filePath := filepath.Join(QweDir, FileName)
 
-	if err := os.WriteFile(filePath, []byte("{}"), TrackFilePermissions); err != nil {
+	if err := cp.WriteFile(filePath, []byte("{}")); err != nil {
 		return fmt.Errorf("create tracked files file: %w", err)
 	}
 
-	if err := cp.CompressFile(filePath); err != nil {
-		return fmt.Errorf("compress file: %w", err)
-	}
-
 	return nil
 }
 
@@ -287,28 +272,21 @@ func findTrackedFilesInCurrDir(dir, workingDir string, trackedFiles *TrackFiles)
 func LoadTrackedFilesFromFile(filename string) (TrackFiles, error) {
 	trackedFiles := make(TrackFiles)
 
-	if err := cp.DecompressFile(filename); err != nil {
-		return nil, err
-	}
-
-	// If file doesn't exist, return empty map
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		return trackedFiles, nil
-	} else if err != nil {
-		return nil, fmt.Errorf("stat file: %w", err)
+	// The index of the repository is kept in memory for the running command
+	var bytes []byte
+	var err error
+	if filepath.Clean(filename) == filepath.Join(QweDir, FileName) {
+		bytes, err = activeSession().read(FileName)
+	} else {
+		bytes, err = cp.ReadFile(filename)
 	}
-
-	bytes, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("read file: %w", err)
+		return nil, err
 	}
 
 	if err := json.Unmarshal(bytes, &trackedFiles); err != nil {
 		return nil, fmt.Errorf("unmarshal tracked files: %w", err)
 	}
-	if err := cp.CompressFile(filename); err != nil {
-		return nil, err
-	}
 	return trackedFiles, nil
 }
 
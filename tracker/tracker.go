@@ -1,13 +1,12 @@
 package tracker
 
 import (
-	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	bh "github.com/mainak55512/qwe/binaryhandler"
@@ -26,6 +25,7 @@ type VersionDetails struct {
 	UID           string `json:"uid"`
 	CommitMessage string `json:"commit_message"`
 	TimeStamp     string `json:"time_stamp"`
+	Author        string `json:"author,omitempty"`
 }
 
 type Tracker struct {
@@ -38,6 +38,24 @@ func (tr *Tracker) CommitsCount() int {
 	return len(tr.Versions)
 }
 
+// Returns the commit id of a commit UID, -2 for the base version.
+// Returns false if the UID is not part of the history. Group versions can refer to such commits when committing
+// the file failed or the commit was squashed or pruned away, the file is then left out of the group version.
+func (tr *Tracker) CommitID(uid string) (int, bool) {
+	if uid == "" {
+		return 0, false
+	}
+	if uid == tr.Base {
+		return -2, true
+	}
+	for i := range tr.Versions {
+		if tr.Versions[i].UID == uid {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
 func (tr *Tracker) LastVersion() *VersionDetails {
 	if len(tr.Versions) == 0 {
 		return nil
@@ -45,14 +63,21 @@ func (tr *Tracker) LastVersion() *VersionDetails {
 	return &tr.Versions[len(tr.Versions)-1]
 }
 
+// A file of a group version. UID is the commit of the file the version refers to,
+// the base object id for the base version and empty if the file could not be committed.
 type FileDetails struct {
-	FileName     string `json:"file_name"`
-	CommitNumber int    `json:"commit_number"`
-	FileObjID    string `json:"file_obj_id"`
+	FileName string `json:"file_name"`
+	UID      string `json:"uid"`
+
+	// Written by earlier versions that referred to commits by their position, only read to migrate the tracker
+	CommitNumber *int   `json:"commit_number,omitempty"`
+	FileObjID    string `json:"file_obj_id,omitempty"`
 }
 
 type GroupVersionDetails struct {
 	CommitMessage string                 `json:"commit_message"`
+	TimeStamp     string                 `json:"time_stamp,omitempty"`
+	Author        string                 `json:"author,omitempty"`
 	Files         map[string]FileDetails `json:"files"`
 }
 
@@ -61,6 +86,7 @@ type GroupTracker struct {
 	Current      string                         `json:"current"`
 	VersionOrder []string                       `json:"version_order"`
 	Versions     map[string]GroupVersionDetails `json:"versions"`
+	Untracked    []string                       `json:"untracked,omitempty"` // ids of files left out of the next group commit
 }
 
 type TrackerSchema map[string]Tracker
@@ -71,95 +97,48 @@ func GetTracker(trackerType int) (TrackerSchema, GroupTrackerSchema, error) {
 	var tracker_schema TrackerSchema
 	var group_tracker_schema GroupTrackerSchema
 
-	var trackerPath string
-
 	// 0 is associated with file tracker, 1 is associated with group tracker
+	var target any
+	name := trackerFile
 	if trackerType == FileTrackerType {
-		trackerPath = ".qwe/_tracker.qwe"
+		target = &tracker_schema
 	} else if trackerType == GroupTrackerType {
-		trackerPath = ".qwe/_group_tracker.qwe"
+		name = groupTrackerFile
+		target = &group_tracker_schema
 	} else {
 		return nil, nil, er.InvalidTracker
 	}
 
-	// Decompress _tracker.qwe
-	if err := cp.DecompressFile(trackerPath); err != nil {
-		return nil, nil, err
-	}
-
-	file, err := os.Open(trackerPath)
+	// The tracker is decompressed once per command and kept in memory
+	current_tracker, err := activeSession().read(name)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	reader := bufio.NewReader(file)
-	current_tracker, err := io.ReadAll(reader)
-	if err != nil {
-		file.Close()
-		return nil, nil, er.TrackerAccessErr
-	} else {
-
-		if trackerType == FileTrackerType {
-			// Parse the content of the tracker file
-			if err := json.Unmarshal(current_tracker, &tracker_schema); err != nil {
-				file.Close()
-				cp.CompressFile(trackerPath)
-				return nil, nil, er.TrackerParseErr
-			}
-		} else {
-			// Parse the content of the tracker file
-			if err := json.Unmarshal(current_tracker, &group_tracker_schema); err != nil {
-				file.Close()
-				cp.CompressFile(trackerPath)
-				return nil, nil, er.TrackerParseErr
-			}
-		}
+	// Parse the content of the tracker file
+	if err := json.Unmarshal(current_tracker, target); err != nil {
+		return nil, nil, er.TrackerParseErr
 	}
-	file.Close()
 
-	// Compress the tracker
-	if err = cp.CompressFile(trackerPath); err != nil {
-		return nil, nil, err
+	// Group trackers written by earlier versions refer to file commits by position
+	if trackerType == GroupTrackerType && needsMigration(group_tracker_schema) {
+		if err = migrateGroupTracker(group_tracker_schema); err != nil {
+			return nil, nil, err
+		}
 	}
 	return tracker_schema, group_tracker_schema, nil
 }
 
-// Updates _tracker.qwe file
+// Updates _tracker.qwe or _group_tracker.qwe file
 func SaveTracker(trackerType int, content []byte) error {
 
-	var trackerPath string
-
 	// 0 is associated with file tracker, 1 is associated with group tracker
 	if trackerType == FileTrackerType {
-		trackerPath = ".qwe/_tracker.qwe"
+		return activeSession().write(trackerFile, content)
 	} else if trackerType == GroupTrackerType {
-		trackerPath = ".qwe/_group_tracker.qwe"
-	} else {
-		return er.InvalidTracker
+		return activeSession().write(groupTrackerFile, content)
 	}
-
-	// Truncate the tracker file
-	tracker_content, err := os.Create(trackerPath)
-	if err != nil {
-		return err
-	}
-
-	// Write the new content on the tracker file
-	writer := bufio.NewWriter(tracker_content)
-	_, err = writer.Write(content)
-	if err != nil {
-		return er.BaseWriteErr
-	}
-	if err = writer.Flush(); err != nil {
-		return er.TrackerWriteErr
-	}
-	tracker_content.Close()
-
-	// Compress the tracker file
-	if err = cp.CompressFile(trackerPath); err != nil {
-		return err
-	}
-	return nil
+	return er.InvalidTracker
 }
 
 // Creates an entry for the file in Tracker and generates a base varient of the file
@@ -192,34 +171,23 @@ func StartTracking(filePath string) (string, error) {
 		if err != nil {
 			return "", err
 		}
+		defer src.Close()
 		fileObjectId = "_bin_" + utl.Hasher(fmt.Sprintf("%s%d", filePath, time.Now().UnixNano()))
-		target := ".qwe/_object/" + fileObjectId
-		dest, err := os.Create(target)
-		if err != nil {
-			return "", err
-		}
-		if _, err := io.Copy(dest, src); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
-			return "", err
-		}
-		if err = cp.CompressFile(target); err != nil {
+		if err = cp.Copy(".qwe/_object/"+fileObjectId, src); err != nil {
 			return "", err
 		}
 	} else {
 
-		base_content, err := os.ReadFile(filePath)
+		src, err := os.Open(filePath)
 		if err != nil {
 			return "", fmt.Errorf("File not found: %s", filePath)
 		}
+		defer src.Close()
 
-		// (Need to change to a buffered writer) write the content of the file to the base varient
-		if err := os.WriteFile(".qwe/_object/"+fileObjectId, base_content, 0644); err != nil {
+		// Compress the content of the file to the base varient
+		if err = cp.Copy(".qwe/_object/"+fileObjectId, src); err != nil {
 			return "", er.TrackUnsuccessful
 		}
-
-		// Compress the base file
-		if err = cp.CompressFile(".qwe/_object/" + fileObjectId); err != nil {
-			return "", err
-		}
 	}
 
 	// Add tracker entry for the file
@@ -312,26 +280,18 @@ func fileTracker(filePath string, groupName string, groupTracker GroupTrackerSch
 		}
 		_, ok = val.Versions[val.Current].Files[fileId]
 		if ok {
-			return groupTracker, fmt.Errorf("File %s is already tracked in group %s", filePath, groupName)
-		}
-		var commitNumber int
-
-		// if current version of the file is a base file
-		// if strings.HasPrefix(f.Current, "_base_") {
-		if f.Current == f.Base {
-			commitNumber = -2 // means for revert we need to revert back to base version
-		} else {
-			for i, elem := range f.Versions {
-				if elem.UID == f.Current {
-					commitNumber = i
-					break
-				}
+			// A file that is going to be left out of the next group commit is kept again
+			if i := slices.Index(val.Untracked, fileId); i != -1 {
+				val.Untracked = slices.Delete(val.Untracked, i, i+1)
+				groupTracker[groupId] = val
+				fmt.Println("Started tracking", filePath, "for group", groupName, "again")
+				return groupTracker, nil
 			}
+			return groupTracker, fmt.Errorf("File %s is already tracked in group %s", filePath, groupName)
 		}
 		val.Versions[val.Current].Files[fileId] = FileDetails{
-			FileName:     filePath,
-			CommitNumber: commitNumber,
-			FileObjID:    f.Current,
+			FileName: filePath,
+			UID:      f.Current,
 		}
 		groupTracker[groupId] = val
 		fmt.Println("Started tracking", filePath, "for group", groupName)
@@ -345,11 +305,10 @@ func fileTracker(filePath string, groupName string, groupTracker GroupTrackerSch
 			return groupTracker, er.InvalidGroup
 		}
 
-		// As the file is first time tracked, the commit id is set to -2, that indicates, in case of revert, need to revert back to base version
+		// As the file is first time tracked, the group refers to its base version
 		val.Versions[val.Current].Files[fileId] = FileDetails{
-			FileName:     filePath,
-			CommitNumber: -2,
-			FileObjID:    fileObjectId,
+			FileName: filePath,
+			UID:      fileObjectId,
 		}
 		groupTracker[groupId] = val
 	}
@@ -3,7 +3,6 @@ package tracker
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
 
 	er "github.com/mainak55512/qwe/qwerror"
@@ -23,15 +22,10 @@ func StopTracking(filePath string) error {
 	}
 
 	fileID := utl.Hasher(filePath)
-	f, ok := tracker[fileID]
-
-	if !ok {
+	if _, ok := tracker[fileID]; !ok {
 		return er.FileNotTracked
 	}
 
-	base_file := f.Base
-	file_versions := f.Versions
-
 	_, groupTracker, err := GetTracker(GroupTrackerType)
 	if err != nil {
 		return err
@@ -42,6 +36,17 @@ func StopTracking(filePath string) error {
 		return err
 	}
 
+	untrack(tracker, groupTracker, trackedFiles, fileID)
+	if err = saveUntracked(tracker, groupTracker, trackedFiles); err != nil {
+		return err
+	}
+
+	fmt.Println("Stopped tracking", filePath)
+	return nil
+}
+
+// Removes a file from the trackers and from every group version
+func untrack(tracker TrackerSchema, groupTracker GroupTrackerSchema, trackedFiles TrackFiles, fileID string) {
 	delete(tracker, fileID)
 	delete(trackedFiles, fileID)
 
@@ -52,7 +57,10 @@ func StopTracking(filePath string) error {
 		}
 		groupTracker[groupID] = group
 	}
+}
 
+// Saves the trackers after files were untracked
+func saveUntracked(tracker TrackerSchema, groupTracker GroupTrackerSchema, trackedFiles TrackFiles) error {
 	trackerContent, err := json.MarshalIndent(tracker, "", " ")
 	if err != nil {
 		return er.CommitUnsuccessful
@@ -63,22 +71,12 @@ func StopTracking(filePath string) error {
 	}
 
 	// Save the individual tracker last so a partial write can be retried.
+	// Stored objects are kept, so the untracking can be undone from the reflog.
 	if err := SaveTracker(GroupTrackerType, groupTrackerContent); err != nil {
 		return err
 	}
 	if err := trackedFiles.Save(); err != nil {
 		return err
 	}
-	if err := SaveTracker(FileTrackerType, trackerContent); err != nil {
-		return err
-	}
-
-	os.Remove(filepath.Join(QweDir, "_object", base_file))
-
-	for _, fl := range file_versions {
-		os.Remove(filepath.Join(QweDir, "_object", fl.UID))
-	}
-
-	fmt.Println("Stopped tracking", filePath)
-	return nil
+	return SaveTracker(FileTrackerType, trackerContent)
 }
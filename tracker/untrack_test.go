@@ -58,13 +58,13 @@ func TestStopTracking(t *testing.T) {
 			Versions: map[string]GroupVersionDetails{
 				"first-base": {
 					Files: map[string]FileDetails{
-						targetID: {FileName: targetPath, CommitNumber: -2, FileObjID: targetObjectID},
+						targetID: {FileName: targetPath, UID: targetObjectID},
 					},
 				},
 				"first-current": {
 					Files: map[string]FileDetails{
-						targetID: {FileName: targetPath, CommitNumber: -2, FileObjID: targetObjectID},
-						keptID:   {FileName: keptPath, CommitNumber: -2, FileObjID: keptObjectID},
+						targetID: {FileName: targetPath, UID: targetObjectID},
+						keptID:   {FileName: keptPath, UID: keptObjectID},
 					},
 				},
 			},
@@ -76,7 +76,7 @@ func TestStopTracking(t *testing.T) {
 			Versions: map[string]GroupVersionDetails{
 				"second-current": {
 					Files: map[string]FileDetails{
-						targetID: {FileName: targetPath, CommitNumber: -2, FileObjID: targetObjectID},
+						targetID: {FileName: targetPath, UID: targetObjectID},
 					},
 				},
 			},
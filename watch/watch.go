@@ -0,0 +1,295 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	cm "github.com/mainak55512/qwe/commit"
+	hs "github.com/mainak55512/qwe/history"
+	er "github.com/mainak55512/qwe/qwerror"
+	utl "github.com/mainak55512/qwe/qweutils"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+// Commit messages of automatic commits start with this prefix, it is used to find them when squashing
+const AutoCommitPrefix = "Auto-commit"
+
+// Timing of the watcher
+type Options struct {
+	Interval time.Duration // How often files are checked
+	Debounce time.Duration // How long a file must stay unchanged before it is committed
+	MinGap   time.Duration // Minimum time between two automatic commits of the same file or group
+}
+
+// Default timing, used for zero values
+var DefaultOptions = Options{
+	Interval: 2 * time.Second,
+	Debounce: 3 * time.Second,
+	MinGap:   time.Minute,
+}
+
+// Size and modification time of a file, a change of either means the file was saved
+type fileState struct {
+	size    int64
+	modTime time.Time
+	exists  bool
+}
+
+func stat(filePath string) fileState {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{size: info.Size(), modTime: info.ModTime(), exists: true}
+}
+
+// A tracked file or group that is committed as a whole
+type target struct {
+	name       string
+	isGroup    bool
+	files      []string
+	states     map[string]fileState
+	lastChange time.Time
+	lastCommit time.Time
+	pending    bool
+}
+
+// Polls the targets and commits them once they settle
+type watcher struct {
+	options Options
+	targets []*target
+	commit  func(t *target, message string) error
+}
+
+// Resolves the arguments to targets, without arguments every tracked file is watched.
+// A file that is part of a watched group is only committed with its group.
+func resolveTargets(names []string) ([]*target, error) {
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		trackedFiles, err := tr.LoadTrackedFilesFromFile(filepath.Join(tr.QweDir, tr.FileName))
+		if err != nil {
+			return nil, err
+		}
+		for fileID, f := range trackedFiles {
+			if _, ok := tracker[fileID]; ok {
+				names = append(names, f.FilePath)
+			}
+		}
+		sort.Strings(names)
+	}
+
+	var targets []*target
+	for _, name := range names {
+		t := &target{name: name, states: make(map[string]fileState)}
+		if _, ok := tracker[utl.Hasher(name)]; ok {
+			t.files = []string{name}
+		} else {
+			_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+			if err != nil {
+				return nil, err
+			}
+			gr, ok := groupTracker[utl.Hasher(name)]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", er.FileNotTracked, name)
+			}
+			t.isGroup = true
+			for _, f := range gr.Versions[gr.Current].Files {
+				t.files = append(t.files, f.FileName)
+			}
+			sort.Strings(t.files)
+		}
+		for _, filePath := range t.files {
+			t.states[filePath] = stat(filePath)
+		}
+		targets = append(targets, t)
+	}
+
+	// Drop the files of watched groups, otherwise they would be committed twice
+	grouped := make(map[string]bool)
+	for _, t := range targets {
+		if t.isGroup {
+			for _, filePath := range t.files {
+				grouped[filePath] = true
+			}
+		}
+	}
+	targets = slices.DeleteFunc(targets, func(t *target) bool {
+		return !t.isGroup && grouped[t.name]
+	})
+	return targets, nil
+}
+
+// Checks every target once, commits the targets that changed and settled
+func (w *watcher) step(now time.Time) {
+	for _, t := range w.targets {
+		for _, filePath := range t.files {
+			state := stat(filePath)
+			if state != t.states[filePath] {
+				t.states[filePath] = state
+				t.lastChange = now
+				t.pending = true
+			}
+		}
+		if !t.pending || now.Sub(t.lastChange) < w.options.Debounce || now.Sub(t.lastCommit) < w.options.MinGap {
+			continue
+		}
+
+		t.pending = false
+		message := fmt.Sprintf("%s: %s at %s", AutoCommitPrefix, t.name, now.Format(cm.TimeStampLayout))
+		err := w.commit(t, message)
+		if err != nil && !errors.Is(err, er.NoFileOrDiff) && !errors.Is(err, er.NoGroupChanges) {
+			fmt.Println("Warning: failed to commit", t.name+":", err)
+			continue
+		}
+		if err == nil {
+			t.lastCommit = now
+		}
+	}
+}
+
+func commitTarget(t *target, message string) error {
+	if t.isGroup {
+		return cm.CommitGroup(t.name, message, false, false)
+	}
+	_, _, err := cm.CommitUnit(t.name, message)
+	return err
+}
+
+// Watches tracked files and groups and commits them automatically after they are saved, until interrupted
+func Watch(names []string, options Options) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	if options.Interval <= 0 {
+		options.Interval = DefaultOptions.Interval
+	}
+	if options.Debounce < 0 {
+		options.Debounce = DefaultOptions.Debounce
+	}
+	if options.MinGap < 0 {
+		options.MinGap = DefaultOptions.MinGap
+	}
+
+	targets, err := resolveTargets(names)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return er.FileNotTracked
+	}
+
+	w := &watcher{options: options, targets: targets, commit: commitTarget}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("Watching %d targets, press Ctrl+C to stop\n", len(targets))
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// Commit what is still pending so no save is lost
+			for _, t := range w.targets {
+				t.lastChange, t.lastCommit = time.Time{}, time.Time{}
+			}
+			w.step(time.Now())
+			fmt.Println("Stopped watching")
+			return nil
+		case now := <-ticker.C:
+			w.step(now)
+		}
+	}
+}
+
+// Returns the runs of consecutive automatic commits as first and last commit id. The latest run comes first,
+// so squashing a run keeps the ids of the runs that follow it valid.
+func autoRuns(messages []string) [][2]int {
+	var runs [][2]int
+	end := len(messages) - 1
+	for end > 0 {
+		if !strings.HasPrefix(messages[end], AutoCommitPrefix) {
+			end--
+			continue
+		}
+		start := end
+		for start > 0 && strings.HasPrefix(messages[start-1], AutoCommitPrefix) {
+			start--
+		}
+		if start < end {
+			runs = append(runs, [2]int{start, end})
+		}
+		end = start - 1
+	}
+	return runs
+}
+
+// Squashes every run of consecutive automatic commits of a file, or of the files of a group, into one commit.
+// For a group the runs of automatic group versions are squashed as well.
+func SquashAuto(name string) error {
+	if !utl.QweIsInWorkingDir() {
+		return er.RepoNotFound
+	}
+	targets, err := resolveTargets([]string{name})
+	if err != nil {
+		return err
+	}
+
+	squashed := 0
+	for _, filePath := range targets[0].files {
+		tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+		if err != nil {
+			return err
+		}
+		versions := tracker[utl.Hasher(filePath)].Versions
+		messages := make([]string, len(versions))
+		for i, version := range versions {
+			messages[i] = version.CommitMessage
+		}
+		for _, run := range autoRuns(messages) {
+			message := fmt.Sprintf("%s: %d changes of %s until %s", AutoCommitPrefix, run[1]-run[0]+1, filePath, versions[run[1]].TimeStamp)
+			if err := hs.Squash(filePath, run[0], run[1], message); errors.Is(err, er.CheckedOutRemoved) {
+				fmt.Printf("Skipped commits %d to %d of %s, the checked out commit is one of them\n", run[0], run[1], filePath)
+				continue
+			} else if err != nil {
+				return err
+			}
+			squashed++
+		}
+	}
+
+	if targets[0].isGroup {
+		versions, err := cm.GroupCommitList(name)
+		if err != nil {
+			return err
+		}
+		messages := make([]string, len(versions))
+		for i, version := range versions {
+			messages[i] = version.CommitMessage
+		}
+		for _, run := range autoRuns(messages) {
+			message := fmt.Sprintf("%s: %d changes of %s until %s", AutoCommitPrefix, run[1]-run[0]+1, name, versions[run[1]].TimeStamp)
+			if err := hs.SquashGroup(name, run[0], run[1], message); errors.Is(err, er.CheckedOutRemoved) {
+				fmt.Printf("Skipped versions %d to %d of group %s, the checked out version is one of them\n", run[0], run[1], name)
+				continue
+			} else if err != nil {
+				return err
+			}
+			squashed++
+		}
+	}
+	if squashed == 0 {
+		fmt.Println("No automatic commits to squash")
+	}
+	return nil
+}
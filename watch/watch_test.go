@@ -0,0 +1,222 @@
+package watch
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	cm "github.com/mainak55512/qwe/commit"
+	in "github.com/mainak55512/qwe/initializer"
+	utl "github.com/mainak55512/qwe/qweutils"
+	res "github.com/mainak55512/qwe/reconstruct"
+	tr "github.com/mainak55512/qwe/tracker"
+)
+
+func writeFile(t *testing.T, filePath, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	// Saves within the same clock tick must still be noticed
+	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+}
+
+func fileTracker(t *testing.T, filePath string) tr.Tracker {
+	t.Helper()
+	tracker, _, err := tr.GetTracker(tr.FileTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read tracker: %v", err)
+	}
+	return tracker[utl.Hasher(filePath)]
+}
+
+func TestWatchAndSquash(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	start := time.Now().Add(-time.Hour)
+	writeFile(t, "notes.txt", "base\n", start)
+	if _, err := tr.StartTracking("notes.txt"); err != nil {
+		t.Fatalf("failed to track file: %v", err)
+	}
+	writeFile(t, "notes.txt", "manual\n", start)
+	if _, _, err := cm.CommitUnit("notes.txt", "Manual"); err != nil {
+		t.Fatalf("failed to commit file: %v", err)
+	}
+
+	targets, err := resolveTargets(nil)
+	if err != nil {
+		t.Fatalf("resolveTargets() failed: %v", err)
+	}
+	if len(targets) != 1 || targets[0].name != "notes.txt" {
+		t.Fatalf("expected notes.txt as the only target, got %+v", targets)
+	}
+	w := &watcher{
+		options: Options{Interval: time.Second, Debounce: 2 * time.Second, MinGap: 10 * time.Second},
+		targets: targets,
+		commit:  commitTarget,
+	}
+
+	now := start
+	save := func(content string) {
+		now = now.Add(time.Second)
+		writeFile(t, "notes.txt", content, now)
+		w.step(now)
+	}
+	commits := func() int { return len(fileTracker(t, "notes.txt").Versions) }
+
+	// Rapid saves are debounced into one commit
+	save("one\n")
+	save("two\n")
+	w.step(now.Add(time.Second))
+	if commits() != 1 {
+		t.Fatalf("expected no commit before the file settled, got %d commits", commits())
+	}
+	now = now.Add(2 * time.Second)
+	w.step(now)
+	if commits() != 2 {
+		t.Fatalf("expected an automatic commit after the file settled, got %d commits", commits())
+	}
+
+	// The next save waits for the minimum gap
+	save("three\n")
+	now = now.Add(3 * time.Second)
+	w.step(now)
+	if commits() != 2 {
+		t.Fatalf("expected the minimum gap to delay the commit, got %d commits", commits())
+	}
+	now = now.Add(10 * time.Second)
+	w.step(now)
+	if commits() != 3 {
+		t.Fatalf("expected a commit after the minimum gap, got %d commits", commits())
+	}
+	now = now.Add(time.Minute)
+	save("four\n")
+	now = now.Add(3 * time.Second)
+	w.step(now)
+
+	val := fileTracker(t, "notes.txt")
+	if len(val.Versions) != 4 || !strings.HasPrefix(val.Versions[3].CommitMessage, AutoCommitPrefix) {
+		t.Fatalf("expected three automatic commits, got %+v", val.Versions)
+	}
+
+	// The group refers to the last automatic commit
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	if err := tr.StartGroupTracking("docs", []string{"notes.txt"}); err != nil {
+		t.Fatalf("failed to track file in group: %v", err)
+	}
+
+	if err := SquashAuto("notes.txt"); err != nil {
+		t.Fatalf("SquashAuto() failed: %v", err)
+	}
+	val = fileTracker(t, "notes.txt")
+	if len(val.Versions) != 2 || val.Versions[0].CommitMessage != "Manual" {
+		t.Fatalf("expected the manual and one squashed commit, got %+v", val.Versions)
+	}
+	if current, _ := val.CommitID(val.Current); current != 1 {
+		t.Errorf("expected commit 1 to be checked out, got %d", current)
+	}
+	for commitID, want := range map[int]string{0: "manual\n", 1: "four\n"} {
+		content, err := res.Content(val, commitID)
+		if err != nil {
+			t.Fatalf("failed to reconstruct commit %d: %v", commitID, err)
+		}
+		if string(content) != want {
+			t.Errorf("commit %d: expected %q, got %q", commitID, want, content)
+		}
+	}
+
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read group tracker: %v", err)
+	}
+	gr := groupTracker[utl.Hasher("docs")]
+	f := gr.Versions[gr.Current].Files[utl.Hasher("notes.txt")]
+	if f.UID != val.Versions[1].UID {
+		t.Errorf("expected the group to refer to the squashed commit, got %+v", f)
+	}
+}
+
+func TestWatchAndSquashGroup(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := in.Init(); err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+	start := time.Now().Add(-time.Hour)
+	writeFile(t, "a.txt", "a\n", start)
+	writeFile(t, "b.txt", "b\n", start)
+	if err := in.GroupInit("docs"); err != nil {
+		t.Fatalf("failed to initialize group: %v", err)
+	}
+	if err := tr.StartGroupTracking("docs", []string{"a.txt", "b.txt"}); err != nil {
+		t.Fatalf("failed to track files in group: %v", err)
+	}
+
+	// Files of a watched group are only committed with the group
+	targets, err := resolveTargets([]string{"a.txt", "docs"})
+	if err != nil {
+		t.Fatalf("resolveTargets() failed: %v", err)
+	}
+	if len(targets) != 1 || targets[0].name != "docs" || !targets[0].isGroup {
+		t.Fatalf("expected docs as the only target, got %+v", targets)
+	}
+	w := &watcher{
+		options: Options{Interval: time.Second, Debounce: time.Second, MinGap: time.Second},
+		targets: targets,
+		commit:  commitTarget,
+	}
+	now := start
+	for _, content := range []string{"one\n", "two\n", "three\n"} {
+		now = now.Add(time.Minute)
+		writeFile(t, "a.txt", content, now)
+		w.step(now)
+		now = now.Add(2 * time.Second)
+		w.step(now)
+	}
+	versions, err := cm.GroupCommitList("docs")
+	if err != nil {
+		t.Fatalf("GroupCommitList() failed: %v", err)
+	}
+	if len(versions) != 4 {
+		t.Fatalf("expected three automatic group commits, got %d versions", len(versions))
+	}
+	if n := len(fileTracker(t, "a.txt").Versions); n != 3 {
+		t.Fatalf("expected a.txt to be committed once per group commit, got %d commits", n)
+	}
+
+	if err := SquashAuto("docs"); err != nil {
+		t.Fatalf("SquashAuto() failed: %v", err)
+	}
+	if n := len(fileTracker(t, "a.txt").Versions); n != 1 {
+		t.Errorf("expected one squashed commit of a.txt, got %d", n)
+	}
+	versions, err = cm.GroupCommitList("docs")
+	if err != nil {
+		t.Fatalf("GroupCommitList() failed: %v", err)
+	}
+	if len(versions) != 2 || !strings.HasPrefix(versions[1].CommitMessage, AutoCommitPrefix+": 3 changes") {
+		t.Fatalf("expected the initial and one squashed group version, got %+v", versions)
+	}
+	val := fileTracker(t, "a.txt")
+	commitID, ok := val.CommitID(versions[1].Files[utl.Hasher("a.txt")].UID)
+	if !ok {
+		t.Fatal("expected the squashed group version to refer to a commit of a.txt")
+	}
+	content, err := res.Content(val, commitID)
+	if err != nil || string(content) != "three\n" {
+		t.Errorf("expected the squashed group version to hold the latest content, got %q, %v", content, err)
+	}
+	_, groupTracker, err := tr.GetTracker(tr.GroupTrackerType)
+	if err != nil {
+		t.Fatalf("failed to read group tracker: %v", err)
+	}
+	if gr := groupTracker[utl.Hasher("docs")]; gr.Current != gr.VersionOrder[1] || len(gr.Versions) != 2 {
+		t.Errorf("expected the squashed version to be checked out and the others removed, got %+v", gr)
+	}
+}